@@ -0,0 +1,200 @@
+// Package alerts evaluates the configured alert rules against the reported metrics, raising events when their
+// thresholds are crossed and when they recover.
+package alerts
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/newrelic/nri-rabbitmq/src/args"
+	"github.com/newrelic/nri-rabbitmq/src/data"
+	"github.com/newrelic/nri-rabbitmq/src/data/consts"
+	"github.com/newrelic/nri-rabbitmq/src/state"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/data/attribute"
+	"github.com/newrelic/infra-integrations-sdk/v3/data/event"
+	"github.com/newrelic/infra-integrations-sdk/v3/data/metric"
+	"github.com/newrelic/infra-integrations-sdk/v3/integration"
+	"github.com/newrelic/infra-integrations-sdk/v3/log"
+	"github.com/newrelic/infra-integrations-sdk/v3/persist"
+)
+
+// target is an entity whose reported sample is checked against the rules
+type target struct {
+	entityType string
+	name       string
+	vhost      string
+	// attributes identify the sample of the target when its entity reports several of them
+	attributes map[string]interface{}
+	getEntity  func() (*integration.Entity, []attribute.Attribute, error)
+}
+
+// EvaluateRules checks args.GlobalArgs.AlertRules against the samples reported for the vhosts and entities. The store
+// keeps the rules crossed by each entity, so events are only raised when a threshold is crossed and when it recovers.
+func EvaluateRules(rabbitmqIntegration *integration.Integration, store persist.Storer, clusterName string, vhosts []*data.VhostData, dataItems ...data.EntityData) {
+	if len(args.GlobalArgs.AlertRules) == 0 {
+		return
+	}
+	targets := make([]target, 0, len(vhosts)+len(dataItems))
+	for _, vhost := range vhosts {
+		vhostName := vhost.Name
+		targets = append(targets, target{consts.VhostType, vhostName, vhostName, nil, func() (*integration.Entity, []attribute.Attribute, error) {
+			return data.CreateEntity(rabbitmqIntegration, vhostName, consts.VhostType, vhostName, clusterName)
+		}})
+	}
+	for _, dataItem := range dataItems {
+		dataItem := dataItem
+		var attributes map[string]interface{}
+		if sampleData, ok := dataItem.(data.SampleData); ok {
+			attributes = sampleData.SampleAttributes()
+		}
+		targets = append(targets, target{dataItem.EntityType(), dataItem.EntityName(), dataItem.EntityVhost(), attributes, func() (*integration.Entity, []attribute.Attribute, error) {
+			return dataItem.GetEntity(rabbitmqIntegration, clusterName)
+		}})
+	}
+
+	for _, t := range targets {
+		rules := matchingRules(t)
+		if len(rules) == 0 {
+			continue
+		}
+		entity, metricNamespace, err := t.getEntity()
+		if err != nil {
+			log.Error("Could not create %s entity [%s]: %v", t.entityType, t.name, err)
+			continue
+		}
+		if entity == nil {
+			continue
+		}
+		metricSet := findMetricSet(entity, t)
+		if metricSet == nil {
+			continue
+		}
+		displayName := getDisplayName(t.name, metricNamespace)
+		if _, ok := metricSet.Metrics[t.entityType+".name"]; ok {
+			// the sample is one of several reported by the entity
+			displayName += "/" + t.name
+		}
+		if groupType, ok := t.attributes[t.entityType+".type"]; ok {
+			displayName += fmt.Sprintf(" (%s)", groupType)
+		}
+		for _, rule := range rules {
+			value, ok := getRuleValue(rule, metricSet)
+			if !ok {
+				log.Debug("Alert rule [%s] can't be evaluated for %s [%s], its metrics are not reported", rule.Name, t.entityType, displayName)
+				continue
+			}
+			evaluateRule(entity, store, rule, t.entityType, displayName, value)
+		}
+	}
+}
+
+// matchingRules returns the rules on the metrics of the target entity type that are not restricted to other entities
+func matchingRules(t target) []*args.AlertRule {
+	var rules []*args.AlertRule
+	for _, rule := range args.GlobalArgs.AlertRules {
+		if rule.EntityType() != t.entityType {
+			continue
+		}
+		if rule.Vhost != "" && rule.Vhost != t.vhost {
+			continue
+		}
+		if rule.EntityRegex != nil && !rule.EntityRegex.MatchString(t.name) {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules
+}
+
+// evaluateRule adds an event when the rule starts or stops being crossed by the entity
+func evaluateRule(entity *integration.Entity, store persist.Storer, rule *args.AlertRule, entityType, displayName string, value float64) {
+	key := fmt.Sprintf("alert/%s/%s:%s", rule.Name, entityType, displayName)
+	change, duration := state.Check(store, key, rule.Crossed(value))
+
+	var description string
+	switch change {
+	case state.NoTransition:
+		return
+	case state.StartedFailing:
+		description = fmt.Sprintf("Alert [%s] triggered for %s [%s]: %s is %s, threshold is %s %s", rule.Name, entityType, displayName, rule.Expression(), formatValue(value), rule.Operator, formatValue(rule.Threshold))
+	case state.Recovered:
+		description = fmt.Sprintf("Alert [%s] recovered for %s [%s]: %s is %s, it was triggered for %s", rule.Name, entityType, displayName, rule.Expression(), formatValue(value), duration)
+	}
+
+	if err := entity.AddEvent(event.New(description, "integration")); err != nil {
+		log.Error("Error adding event: %v", err)
+		return
+	}
+	state.Record(store, key, change)
+}
+
+// findMetricSet returns the sample reported for the target. Entities reporting several samples of the same type, like
+// the queue groups of a vhost, are told apart by the attributes of the target, or by their name attribute otherwise.
+func findMetricSet(entity *integration.Entity, t target) *metric.Set {
+	sampleName := fmt.Sprintf("Rabbitmq%sSample", strings.Title(t.entityType))
+	nameAttribute := t.entityType + ".name"
+	for _, metricSet := range entity.Metrics {
+		if metricSet.Metrics["event_type"] != sampleName {
+			continue
+		}
+		if t.attributes != nil {
+			if hasAttributes(metricSet, t.attributes) {
+				return metricSet
+			}
+			continue
+		}
+		if setName, ok := metricSet.Metrics[nameAttribute]; !ok || setName == t.name {
+			return metricSet
+		}
+	}
+	return nil
+}
+
+func hasAttributes(metricSet *metric.Set, attributes map[string]interface{}) bool {
+	for name, value := range attributes {
+		if metricSet.Metrics[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// getRuleValue returns the value compared by the rule, which can't be computed if a metric is missing or the divisor is zero
+func getRuleValue(rule *args.AlertRule, metricSet *metric.Set) (float64, bool) {
+	value, ok := getMetricValue(metricSet, rule.Metric)
+	if !ok || rule.Divisor == "" {
+		return value, ok
+	}
+	divisor, ok := getMetricValue(metricSet, rule.Divisor)
+	if !ok || divisor == 0 {
+		return 0, false
+	}
+	return value / divisor, true
+}
+
+func getMetricValue(metricSet *metric.Set, metricName string) (float64, bool) {
+	switch v := metricSet.Metrics[metricName].(type) {
+	case float64:
+		return v, true
+	case int:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	}
+	return 0, false
+}
+
+func getDisplayName(entityName string, metricNamespace []attribute.Attribute) string {
+	for _, attr := range metricNamespace {
+		if attr.Key == "displayName" {
+			return attr.Value
+		}
+	}
+	return entityName
+}
+
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
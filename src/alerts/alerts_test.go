@@ -0,0 +1,118 @@
+package alerts
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/newrelic/nri-rabbitmq/src/args"
+	"github.com/newrelic/nri-rabbitmq/src/data"
+	"github.com/newrelic/nri-rabbitmq/src/metrics"
+	"github.com/newrelic/nri-rabbitmq/src/state"
+	"github.com/newrelic/nri-rabbitmq/src/testutils"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/integration"
+	"github.com/newrelic/infra-integrations-sdk/v3/persist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMain(m *testing.M) {
+	args.GlobalArgs = args.RabbitMQArguments{
+		Hostname: "foo",
+		Port:     8000,
+	}
+	os.Exit(m.Run())
+}
+
+func setAlertRules(t *testing.T, rules string) {
+	argList := args.ArgumentList{Hostname: "foo", Port: 8000, AlertRules: rules}
+	require.NoError(t, args.SetGlobalArgs(argList))
+}
+
+func collect(t *testing.T, store persist.Storer, queues ...*data.QueueData) *integration.Integration {
+	i := testutils.GetTestingIntegration(t)
+	dataItems := make([]data.EntityData, len(queues))
+	for idx, q := range queues {
+		dataItems[idx] = q
+	}
+	metrics.CollectEntityMetrics(i, nil, "testClusterName", dataItems...)
+	EvaluateRules(i, store, "testClusterName", nil, dataItems...)
+	return i
+}
+
+func getEvents(i *integration.Integration) []string {
+	var events []string
+	for _, e := range i.Entities {
+		for _, ev := range e.Events {
+			events = append(events, ev.Summary)
+		}
+	}
+	return events
+}
+
+func TestEvaluateRules(t *testing.T) {
+	setAlertRules(t, `[{"name": "backlog", "condition": "queue.totalMessages > 100", "vhost": "/orders"}]`)
+	defer func() {
+		state.Now = time.Now
+	}()
+	store := persist.NewInMemoryStore()
+	messages := func(count int64) *int64 { return &count }
+
+	state.Now = func() time.Time { return time.Unix(1000, 0) }
+	i := collect(t, store,
+		&data.QueueData{Name: "q1", Vhost: "/orders", Messages: messages(150)},
+		&data.QueueData{Name: "q1", Vhost: "/other", Messages: messages(150)},
+		&data.QueueData{Name: "q2", Vhost: "/orders", Messages: messages(10)},
+	)
+	assert.Equal(t, []string{"Alert [backlog] triggered for queue [/orders/q1]: queue.totalMessages is 150, threshold is > 100"}, getEvents(i))
+
+	state.Now = func() time.Time { return time.Unix(1060, 0) }
+	i = collect(t, store, &data.QueueData{Name: "q1", Vhost: "/orders", Messages: messages(200)})
+	assert.Empty(t, getEvents(i), "no events are expected while the threshold is still crossed")
+
+	state.Now = func() time.Time { return time.Unix(1300, 0) }
+	i = collect(t, store, &data.QueueData{Name: "q1", Vhost: "/orders", Messages: messages(20)})
+	assert.Equal(t, []string{"Alert [backlog] recovered for queue [/orders/q1]: queue.totalMessages is 20, it was triggered for 5m0s"}, getEvents(i))
+
+	i = collect(t, store, &data.QueueData{Name: "q1", Vhost: "/orders", Messages: messages(20)})
+	assert.Empty(t, getEvents(i))
+}
+
+func TestEvaluateRules_Ratio(t *testing.T) {
+	setAlertRules(t, `[{"name": "fds", "condition": "node.fileDescriptorsTotalUsed / node.fileDescriptorsTotal > 0.9", "entity": "^rabbit@"}]`)
+	store := persist.NewInMemoryStore()
+	count := func(value int64) *int64 { return &value }
+
+	i := testutils.GetTestingIntegration(t)
+	nodes := []data.EntityData{
+		&data.NodeData{Name: "rabbit@host1", FileDescriptorsUsed: count(95), FileDescriptorsTotal: count(100)},
+		&data.NodeData{Name: "rabbit@host2", FileDescriptorsUsed: count(10), FileDescriptorsTotal: count(100)},
+		&data.NodeData{Name: "rabbit@host3", FileDescriptorsUsed: count(10), FileDescriptorsTotal: count(0)},
+		&data.NodeData{Name: "other@host4", FileDescriptorsUsed: count(95), FileDescriptorsTotal: count(100)},
+	}
+	metrics.CollectEntityMetrics(i, nil, "testClusterName", nodes...)
+	EvaluateRules(i, store, "testClusterName", nil, nodes...)
+	assert.Equal(t, []string{"Alert [fds] triggered for node [rabbit@host1]: node.fileDescriptorsTotalUsed / node.fileDescriptorsTotal is 0.95, threshold is > 0.9"}, getEvents(i))
+}
+
+func TestEvaluateRules_NoRules(t *testing.T) {
+	setAlertRules(t, "")
+	i := collect(t, persist.NewInMemoryStore(), &data.QueueData{Name: "q1", Vhost: "/"})
+	assert.Empty(t, getEvents(i))
+}
+
+func TestEvaluateRules_QueueGroups(t *testing.T) {
+	setAlertRules(t, `[{"name": "orders", "condition": "queueGroup.totalMessages > 100", "entity": "^orders$"}]`)
+	store := persist.NewInMemoryStore()
+
+	i := testutils.GetTestingIntegration(t)
+	dataItems := []data.EntityData{
+		&data.QueueGroupData{Vhost: "vhost1", Name: data.AllQueuesGroup, Type: data.QueueGroupTypeAll, Messages: 150},
+		&data.QueueGroupData{Vhost: "vhost1", Name: "orders", Type: data.QueueGroupTypeRegex, Messages: 50},
+		&data.QueueGroupData{Vhost: "vhost1", Name: "orders", Type: data.QueueGroupTypeTemplate, Messages: 120},
+	}
+	metrics.CollectEntityMetrics(i, nil, "testClusterName", dataItems...)
+	EvaluateRules(i, store, "testClusterName", nil, dataItems...)
+	assert.Equal(t, []string{"Alert [orders] triggered for queueGroup [vhost1/orders (template)]: queueGroup.totalMessages is 120, threshold is > 100"}, getEvents(i), "groups with the same name are told apart by their type")
+}
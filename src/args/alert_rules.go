@@ -0,0 +1,130 @@
+package args
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// AlertRule is a threshold on a reported metric, or on the ratio of two metrics of the same sample
+type AlertRule struct {
+	Name      string `json:"name"`
+	Condition string `json:"condition"`
+	// Vhost restricts the rule to the entities of a vhost
+	Vhost string `json:"vhost"`
+	// Entity is a regex restricting the rule to the entities whose names match it
+	Entity      string         `json:"entity"`
+	EntityRegex *regexp.Regexp `json:"-"`
+	// Metric is the metric compared to the threshold, divided by Divisor when it's set
+	Metric    string  `json:"-"`
+	Divisor   string  `json:"-"`
+	Operator  string  `json:"-"`
+	Threshold float64 `json:"-"`
+}
+
+// alertOperators are ordered so two character operators are matched before their one character prefixes
+var alertOperators = []string{">=", "<=", "==", "!=", ">", "<"}
+
+// EntityType returns the type of the entities the rule applies to, which is the prefix of its metric names
+func (rule *AlertRule) EntityType() string {
+	entityType, _, _ := strings.Cut(rule.Metric, ".")
+	return entityType
+}
+
+// Crossed returns true if the value is past the threshold
+func (rule *AlertRule) Crossed(value float64) bool {
+	switch rule.Operator {
+	case ">":
+		return value > rule.Threshold
+	case ">=":
+		return value >= rule.Threshold
+	case "<":
+		return value < rule.Threshold
+	case "<=":
+		return value <= rule.Threshold
+	case "==":
+		return value == rule.Threshold
+	case "!=":
+		return value != rule.Threshold
+	}
+	return false
+}
+
+// Expression returns the compared value as it was written in the condition
+func (rule *AlertRule) Expression() string {
+	if rule.Divisor == "" {
+		return rule.Metric
+	}
+	return rule.Metric + " / " + rule.Divisor
+}
+
+// parseAlertRules parses a JSON array of rules, e.g. [{"name": "backlog", "condition": "queue.totalMessages > 100000", "vhost": "/orders"}]
+func parseAlertRules(argValue string) ([]*AlertRule, error) {
+	if argValue == "" {
+		return nil, nil
+	}
+	var rules []*AlertRule
+	if err := json.Unmarshal([]byte(argValue), &rules); err != nil {
+		return nil, err
+	}
+	names := make(map[string]bool, len(rules))
+	for i, rule := range rules {
+		if rule.Name == "" {
+			return nil, fmt.Errorf("alert rule %d has no name", i)
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("alert rule [%s] is declared more than once", rule.Name)
+		}
+		names[rule.Name] = true
+		if err := rule.parseCondition(); err != nil {
+			return nil, fmt.Errorf("alert rule [%s]: %v", rule.Name, err)
+		}
+		if rule.Entity != "" {
+			regex, err := regexp.Compile(rule.Entity)
+			if err != nil {
+				return nil, fmt.Errorf("alert rule [%s]: %v", rule.Name, err)
+			}
+			rule.EntityRegex = regex
+		}
+	}
+	return rules, nil
+}
+
+// parseCondition parses conditions like "queue.totalMessages > 100000" or "node.fileDescriptorsTotalUsed / node.fileDescriptorsTotal > 0.9"
+func (rule *AlertRule) parseCondition() error {
+	var expression, threshold string
+	for _, operator := range alertOperators {
+		if index := strings.Index(rule.Condition, operator); index >= 0 {
+			expression, threshold = rule.Condition[:index], rule.Condition[index+len(operator):]
+			rule.Operator = operator
+			break
+		}
+	}
+	if rule.Operator == "" {
+		return fmt.Errorf("condition [%s] has no comparison operator", rule.Condition)
+	}
+
+	value, err := strconv.ParseFloat(strings.TrimSpace(threshold), 64)
+	if err != nil {
+		return fmt.Errorf("condition [%s] must compare to a number", rule.Condition)
+	}
+	rule.Threshold = value
+
+	metricName, divisor, isRatio := strings.Cut(expression, "/")
+	rule.Metric = strings.TrimSpace(metricName)
+	rule.Divisor = strings.TrimSpace(divisor)
+	if rule.Metric == "" || (isRatio && rule.Divisor == "") {
+		return fmt.Errorf("condition [%s] is missing a metric name", rule.Condition)
+	}
+	if !strings.Contains(rule.Metric, ".") {
+		return fmt.Errorf("condition [%s] metric [%s] must be prefixed by its entity type, e.g. queue.totalMessages", rule.Condition, rule.Metric)
+	}
+	if isRatio {
+		if divisorType, _, _ := strings.Cut(rule.Divisor, "."); divisorType != rule.EntityType() {
+			return fmt.Errorf("condition [%s] divides metrics of different entity types", rule.Condition)
+		}
+	}
+	return nil
+}
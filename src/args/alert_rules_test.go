@@ -0,0 +1,74 @@
+package args
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseAlertRules(t *testing.T) {
+	rules, err := parseAlertRules(`[
+		{"name": "backlog", "condition": "queue.totalMessages > 100000", "vhost": "/orders"},
+		{"name": "fds", "condition": "node.fileDescriptorsTotalUsed / node.fileDescriptorsTotal >= 0.9", "entity": "^rabbit@"}
+	]`)
+	require.NoError(t, err)
+	require.Len(t, rules, 2)
+
+	assert.Equal(t, "queue", rules[0].EntityType())
+	assert.Equal(t, "queue.totalMessages", rules[0].Expression())
+	assert.Equal(t, ">", rules[0].Operator)
+	assert.Equal(t, float64(100000), rules[0].Threshold)
+	assert.Equal(t, "/orders", rules[0].Vhost)
+	assert.Nil(t, rules[0].EntityRegex)
+
+	assert.Equal(t, "node", rules[1].EntityType())
+	assert.Equal(t, "node.fileDescriptorsTotalUsed / node.fileDescriptorsTotal", rules[1].Expression())
+	assert.Equal(t, ">=", rules[1].Operator)
+	assert.Equal(t, 0.9, rules[1].Threshold)
+	assert.True(t, rules[1].EntityRegex.MatchString("rabbit@host"))
+
+	rules, err = parseAlertRules("")
+	assert.NoError(t, err)
+	assert.Nil(t, rules)
+}
+
+func Test_parseAlertRules_Errors(t *testing.T) {
+	invalid := []string{
+		`[,]`,
+		`[{"condition": "queue.totalMessages > 1"}]`,
+		`[{"name": "a", "condition": "queue.totalMessages > 1"}, {"name": "a", "condition": "queue.consumers < 1"}]`,
+		`[{"name": "a", "condition": "queue.totalMessages"}]`,
+		`[{"name": "a", "condition": "queue.totalMessages > many"}]`,
+		`[{"name": "a", "condition": " > 1"}]`,
+		`[{"name": "a", "condition": "totalMessages > 1"}]`,
+		`[{"name": "a", "condition": "queue.totalMessages / > 1"}]`,
+		`[{"name": "a", "condition": "queue.totalMessages / node.fileDescriptorsTotal > 1"}]`,
+		`[{"name": "a", "condition": "queue.totalMessages > 1", "entity": "(invalid-group"}]`,
+	}
+	for _, value := range invalid {
+		_, err := parseAlertRules(value)
+		assert.Error(t, err, value)
+	}
+}
+
+func TestAlertRule_Crossed(t *testing.T) {
+	tests := []struct {
+		operator string
+		value    float64
+		expected bool
+	}{
+		{">", 11, true},
+		{">", 10, false},
+		{">=", 10, true},
+		{"<", 9, true},
+		{"<", 10, false},
+		{"<=", 10, true},
+		{"==", 10, true},
+		{"!=", 10, false},
+	}
+	for _, tc := range tests {
+		rule := &AlertRule{Operator: tc.operator, Threshold: 10}
+		assert.Equal(t, tc.expected, rule.Crossed(tc.value), "%v %s 10", tc.value, tc.operator)
+	}
+}
@@ -5,27 +5,60 @@ import sdkArgs "github.com/newrelic/infra-integrations-sdk/v3/args"
 // ArgumentList is the raw arguments passed into the integration via YAML, CLI args, or ENV variables
 type ArgumentList struct {
 	sdkArgs.DefaultArgumentList
-	Hostname             string `default:"localhost" help:"Hostname or IP where RabbitMQ Management Plugin is running."`
-	Port                 int    `default:"15672" help:"Port on which RabbitMQ Management Plugin is listening."`
-	Username             string `default:"" help:"Username for accessing RabbitMQ Management Plugin"`
-	Password             string `default:"" help:"Password for the given user."`
-	ManagementPathPrefix string `default:"" help:"RabbitMQ Management Prefix."`
-	CABundleFile         string `default:"" help:"Alternative Certificate Authority bundle file"`
-	CABundleDir          string `default:"" help:"Alternative Certificate Authority bundle directory"`
-	NodeNameOverride     string `default:"" help:"Overrides the local node name instead of retrieving it from RabbitMQ."`
-	ConfigPath           string `default:"" help:"RabbitMQ configuration file path."`
-	UseSSL               bool   `default:"false" help:"configure whether to use an SSL connection or not."`
-	Queues               string `default:"" help:"JSON array of queue names from which to collect metrics."`
-	QueuesRegexes        string `default:"" help:"JSON array of queue name regexes from which to collect metrics."`
-	Exchanges            string `default:"" help:"JSON array of exchange names from which to collect metrics."`
-	ExchangesRegexes     string `default:"" help:"JSON array of exchange name regexes from which to collect metrics."`
-	Vhosts               string `default:"" help:"JSON array of vhost names from which to collect metrics."`
-	VhostsRegexes        string `default:"" help:"JSON array of vhost name regexes from which to collect metrics."`
-	ShowVersion          bool   `default:"false" help:"Print build information and exit"`
-	Timeout              int    `default:"30" help:"Timeout in seconds to timeout the connection to RabbitMQ endpoint."`
+	Hostname                string `default:"localhost" help:"Hostname or IP where RabbitMQ Management Plugin is running."`
+	Port                    int    `default:"15672" help:"Port on which RabbitMQ Management Plugin is listening."`
+	Username                string `default:"" help:"Username for accessing RabbitMQ Management Plugin"`
+	Password                string `default:"" help:"Password for the given user."`
+	BearerToken             string `default:"" help:"Bearer token sent to the Management API instead of the username and password, as used by the OAuth 2.0 plugin."`
+	BearerTokenFile         string `default:"" help:"File containing the bearer token, re-read on every run so rotated tokens, like Kubernetes projected tokens, are picked up."`
+	OauthTokenURL           string `default:"" help:"OAuth 2.0 token endpoint from which a bearer token is requested on every run with the client credentials grant."`
+	OauthClientID           string `default:"" help:"Client ID for the OAuth 2.0 client credentials grant."`
+	OauthClientSecret       string `default:"" help:"Client secret for the OAuth 2.0 client credentials grant."`
+	OauthScope              string `default:"" help:"Space separated scopes requested with the OAuth 2.0 client credentials grant."`
+	ManagementPathPrefix    string `default:"" help:"RabbitMQ Management Prefix."`
+	CABundleFile            string `default:"" help:"Alternative Certificate Authority bundle file"`
+	CABundleDir             string `default:"" help:"Alternative Certificate Authority bundle directory"`
+	NodeNameOverride        string `default:"" help:"Overrides the local node name instead of retrieving it from RabbitMQ."`
+	ConfigPath              string `default:"" help:"RabbitMQ configuration file path. Files ending in .config are parsed as Erlang terms (advanced.config, rabbitmq.config), the conf.d fragments and the advanced.config next to a .conf file are also included."`
+	EffectiveConfig         bool   `default:"false" help:"Collect the node's effective application environment as inventory, flagging values that differ from the configuration files."`
+	EffectiveConfigPath     string `default:"" help:"File containing the output of 'rabbitmq-diagnostics environment'. If empty, the command is executed locally when EffectiveConfig is enabled."`
+	UseSSL                  bool   `default:"false" help:"configure whether to use an SSL connection or not."`
+	Queues                  string `default:"" help:"JSON array of queue names from which to collect metrics."`
+	QueuesRegexes           string `default:"" help:"JSON array of queue name regexes from which to collect metrics."`
+	Exchanges               string `default:"" help:"JSON array of exchange names from which to collect metrics."`
+	ExchangesRegexes        string `default:"" help:"JSON array of exchange name regexes from which to collect metrics."`
+	Vhosts                  string `default:"" help:"JSON array of vhost names from which to collect metrics."`
+	VhostsRegexes           string `default:"" help:"JSON array of vhost name regexes from which to collect metrics."`
+	QueuesExclude           string `default:"" help:"JSON array of queue names not to collect, takes precedence over Queues and QueuesRegexes."`
+	QueuesExcludeRegexes    string `default:"" help:"JSON array of queue name regexes not to collect, e.g. [\"^amq\\\\.gen-\"], takes precedence over Queues and QueuesRegexes."`
+	ExchangesExclude        string `default:"" help:"JSON array of exchange names not to collect, takes precedence over Exchanges and ExchangesRegexes."`
+	ExchangesExcludeRegexes string `default:"" help:"JSON array of exchange name regexes not to collect, takes precedence over Exchanges and ExchangesRegexes."`
+	VhostsExclude           string `default:"" help:"JSON array of vhost names whose entities are not collected, takes precedence over Vhosts and VhostsRegexes."`
+	VhostsExcludeRegexes    string `default:"" help:"JSON array of vhost name regexes whose entities are not collected, takes precedence over Vhosts and VhostsRegexes."`
+	Nodes                   string `default:"" help:"JSON array of node names to report as entities."`
+	NodesRegexes            string `default:"" help:"JSON array of node name regexes to report as entities."`
+	NodesExclude            string `default:"" help:"JSON array of node names not to report as entities."`
+	NodesExcludeRegexes     string `default:"" help:"JSON array of node name regexes not to report as entities."`
+	QueuesFilter            string `default:"" help:"JSON object filtering the queues by their properties, e.g. {\"exclusive\": false, \"auto_delete\": false, \"types\": [\"quorum\"], \"exclude_arguments\": [\"x-expires\"]}."`
+	ExchangesFilter         string `default:"" help:"JSON object filtering the exchanges by their properties, e.g. {\"internal\": false, \"exclude_types\": [\"x-delayed-message\"]}."`
+	FiltersConfigPath       string `default:"" help:"YAML file with the queue, exchange, vhost and node filters, including per-vhost rules. It replaces the filter arguments, which can't be set along with it."`
+	LocalNodeOnly           bool   `default:"false" help:"Only report the node running alongside the integration, as resolved for the inventory, so each host reports its own node. The instance of the first running node also reports when the other nodes stop running."`
+	LocalQueuesOnly         bool   `default:"false" help:"Only report the queues whose node is the local node, so each host reports its own queues. The queues are then collected by every instance regardless of ClusterEntities."`
+	ClusterEntities         string `default:"always" help:"Whether this instance reports the cluster-wide entities (vhosts, queues, exchanges, links and the cluster): always, never, or auto to report them only if the local node is the first running node by name, so a single instance per cluster does."`
+	RedactKeysRegexes       string `default:"" help:"JSON array of regexes, inventory keys and URI parameters matching any of them have their values redacted, in addition to the keys containing pass, secret or token, ending in key, and the LDAP bind settings."`
+	AlertRules              string `default:"" help:"JSON array of alert rules, e.g. [{\"name\": \"backlog\", \"condition\": \"queue.totalMessages > 100000\", \"vhost\": \"/orders\"}]. Events are raised when the thresholds are crossed and when they recover."`
+	CountersSourceType      string `default:"gauge" help:"How cumulative message counters (queue.messagesPublished, exchange.messagesPublishedQueue...) are reported: gauge, rate or delta. Rates and deltas are reported with a Rate or Delta suffix."`
+	LegacyCounterGauges     bool   `default:"true" help:"Keep reporting the cumulative message counters as gauges under their original names when CountersSourceType is rate or delta."`
+	ShowVersion             bool   `default:"false" help:"Print build information and exit"`
+	Timeout                 int    `default:"30" help:"Timeout in seconds to timeout the connection to RabbitMQ endpoint."`
 
 	// The reason is that each queue generates an inventory entry (for entity creation proposes)
 	// and the Agent is not capable of processing a higher amount of inventory entries.
-	QueuesMaxLimit  int  `default:"2000" help:"Defines the max amount of Queues that can be processed, if this number is reached all queues will be dropped. If defined as '0' no limits are applied"`
-	DisableEntities bool `default:"false" help:"configure whether inventory entries are created for entities during metrics collection."`
+	QueuesMaxLimit      int    `default:"2000" help:"Defines the max amount of Queues that can be processed, if this number is reached all queues will be dropped. If defined as '0' no limits are applied"`
+	QueuesLimitMode     string `default:"drop" help:"What to do when QueuesMaxLimit is reached: drop all queues, or keep the top queues ranked by QueuesRankBy and summarise the others per vhost in a RabbitmqQueueGroupSample."`
+	QueuesRankBy        string `default:"messages" help:"Ranking of the queues kept in top mode: messages, messages_unacknowledged, publish_rate, memory or consumer_utilisation. Queues are ranked from the highest value, except for consumer_utilisation which ranks the least utilised queues first."`
+	QueuesAggregation   string `default:"none" help:"Summarise the queues of each vhost in a RabbitmqQueueGroupSample: none, vhost to report the summaries along with the queues, or only to report the summaries without any queue sample."`
+	QueueGroupsRegexes  string `default:"" help:"JSON array of queue name regexes, the queues matching each of them are also summarised per vhost in a group named after the regex when QueuesAggregation is enabled."`
+	QueueGroupTemplates string `default:"" help:"JSON array of queue group templates, e.g. [{\"regex\": \"^orders\\\\.(?P<tenant>[^.]+)\\\\.\", \"group\": \"orders.{tenant}\"}]. The queues matching a regex are reported with the queue.group attribute, and summarised per group when QueuesAggregation is enabled."`
+	DisableEntities     bool   `default:"false" help:"configure whether inventory entries are created for entities during metrics collection."`
 }
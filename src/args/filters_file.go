@@ -0,0 +1,254 @@
+package args
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+
+	"gopkg.in/yaml.v3"
+)
+
+// VhostRule replaces the queue or exchange filters for the vhosts it matches. It's set from the vhost_rules of the filters file.
+type VhostRule struct {
+	Vhost      string
+	VhostRegex *regexp.Regexp
+	// Queues and Exchanges are nil when the rule doesn't replace the filters of the entity type
+	Queues    *EntityRule
+	Exchanges *EntityRule
+}
+
+// EntityRule is the name and property filters a VhostRule applies to an entity type
+type EntityRule struct {
+	Names          NameFilter
+	QueueFilter    *QueueFilter
+	ExchangeFilter *ExchangeFilter
+}
+
+// NameFilter is an allow-list and an exclude list of names, the excludes take precedence
+type NameFilter struct {
+	Include        []string
+	IncludeRegexes []*regexp.Regexp
+	Exclude        []string
+	ExcludeRegexes []*regexp.Regexp
+}
+
+// includes returns true if the name is not excluded and is in the allow-list, or there is no allow-list
+func (f *NameFilter) includes(name string) bool {
+	return !matchesName(name, f.Exclude, f.ExcludeRegexes) && includeName(name, f.Include, f.IncludeRegexes)
+}
+
+// matches returns true if the rule applies to the vhost
+func (rule *VhostRule) matches(vhostName string) bool {
+	if rule.VhostRegex != nil {
+		return rule.VhostRegex.MatchString(vhostName)
+	}
+	return rule.Vhost == vhostName
+}
+
+// vhostRule returns the first rule matching the vhost
+func (args *RabbitMQArguments) vhostRule(vhostName string) *VhostRule {
+	for _, rule := range args.VhostRules {
+		if rule.matches(vhostName) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// QueueFilterFor returns the queue property filter applying to the queues of the vhost
+func (args *RabbitMQArguments) QueueFilterFor(vhostName string) *QueueFilter {
+	if rule := args.vhostRule(vhostName); rule != nil && rule.Queues != nil {
+		return rule.Queues.QueueFilter
+	}
+	return args.QueuesFilter
+}
+
+// ExchangeFilterFor returns the exchange property filter applying to the exchanges of the vhost
+func (args *RabbitMQArguments) ExchangeFilterFor(vhostName string) *ExchangeFilter {
+	if rule := args.vhostRule(vhostName); rule != nil && rule.Exchanges != nil {
+		return rule.Exchanges.ExchangeFilter
+	}
+	return args.ExchangesFilter
+}
+
+// filtersFile is the layout of the file referenced by FiltersConfigPath
+type filtersFile struct {
+	Queues     *queueFilters    `yaml:"queues"`
+	Exchanges  *exchangeFilters `yaml:"exchanges"`
+	Vhosts     *nameFilters     `yaml:"vhosts"`
+	Nodes      *nameFilters     `yaml:"nodes"`
+	VhostRules []vhostRule      `yaml:"vhost_rules"`
+}
+
+type nameFilters struct {
+	Include        []string `yaml:"include"`
+	IncludeRegexes []string `yaml:"include_regexes"`
+	Exclude        []string `yaml:"exclude"`
+	ExcludeRegexes []string `yaml:"exclude_regexes"`
+}
+
+type queueFilters struct {
+	nameFilters `yaml:",inline"`
+	Properties  *QueueFilter `yaml:"properties"`
+}
+
+type exchangeFilters struct {
+	nameFilters `yaml:",inline"`
+	Properties  *ExchangeFilter `yaml:"properties"`
+}
+
+type vhostRule struct {
+	Vhost      string           `yaml:"vhost"`
+	VhostRegex string           `yaml:"vhost_regex"`
+	Queues     *queueFilters    `yaml:"queues"`
+	Exchanges  *exchangeFilters `yaml:"exchanges"`
+}
+
+// loadFiltersFile sets the filters of rabbitArgs from the YAML file, e.g.
+//
+//	queues:
+//	  exclude_regexes: ['^amq\.gen-']
+//	  properties:
+//	    exclusive: false
+//	vhost_rules:
+//	  - vhost: /orders
+//	    queues:
+//	      include_regexes: ['^orders\.']
+func loadFiltersFile(path string, rabbitArgs *RabbitMQArguments) error {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	var file filtersFile
+	decoder := yaml.NewDecoder(bytes.NewReader(content))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&file); err != nil && !errors.Is(err, io.EOF) {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	if err := file.apply(rabbitArgs); err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+	return nil
+}
+
+func (file *filtersFile) apply(rabbitArgs *RabbitMQArguments) error {
+	var err error
+	if file.Queues != nil {
+		var names NameFilter
+		if names, err = file.Queues.compile("queues"); err != nil {
+			return err
+		}
+		rabbitArgs.Queues, rabbitArgs.QueuesRegexes = names.Include, names.IncludeRegexes
+		rabbitArgs.QueuesExclude, rabbitArgs.QueuesExcludeRegexes = names.Exclude, names.ExcludeRegexes
+		if rabbitArgs.QueuesFilter, err = file.Queues.compileProperties("queues"); err != nil {
+			return err
+		}
+	}
+	if file.Exchanges != nil {
+		var names NameFilter
+		if names, err = file.Exchanges.compile("exchanges"); err != nil {
+			return err
+		}
+		rabbitArgs.Exchanges, rabbitArgs.ExchangesRegexes = names.Include, names.IncludeRegexes
+		rabbitArgs.ExchangesExclude, rabbitArgs.ExchangesExcludeRegexes = names.Exclude, names.ExcludeRegexes
+		rabbitArgs.ExchangesFilter = file.Exchanges.Properties
+	}
+	if file.Vhosts != nil {
+		var names NameFilter
+		if names, err = file.Vhosts.compile("vhosts"); err != nil {
+			return err
+		}
+		rabbitArgs.Vhosts, rabbitArgs.VhostsRegexes = names.Include, names.IncludeRegexes
+		rabbitArgs.VhostsExclude, rabbitArgs.VhostsExcludeRegexes = names.Exclude, names.ExcludeRegexes
+	}
+	if file.Nodes != nil {
+		var names NameFilter
+		if names, err = file.Nodes.compile("nodes"); err != nil {
+			return err
+		}
+		rabbitArgs.Nodes, rabbitArgs.NodesRegexes = names.Include, names.IncludeRegexes
+		rabbitArgs.NodesExclude, rabbitArgs.NodesExcludeRegexes = names.Exclude, names.ExcludeRegexes
+	}
+
+	for i, rawRule := range file.VhostRules {
+		rule, err := rawRule.compile(fmt.Sprintf("vhost_rules[%d]", i))
+		if err != nil {
+			return err
+		}
+		rabbitArgs.VhostRules = append(rabbitArgs.VhostRules, rule)
+	}
+	return nil
+}
+
+func (rawRule *vhostRule) compile(path string) (*VhostRule, error) {
+	if (rawRule.Vhost == "") == (rawRule.VhostRegex == "") {
+		return nil, fmt.Errorf("%s: exactly one of vhost or vhost_regex must be set", path)
+	}
+	if rawRule.Queues == nil && rawRule.Exchanges == nil {
+		return nil, fmt.Errorf("%s: queues or exchanges must be set", path)
+	}
+	rule := &VhostRule{Vhost: rawRule.Vhost}
+	if rawRule.VhostRegex != "" {
+		regex, err := regexp.Compile(rawRule.VhostRegex)
+		if err != nil {
+			return nil, fmt.Errorf("%s.vhost_regex: %v", path, err)
+		}
+		rule.VhostRegex = regex
+	}
+	if rawRule.Queues != nil {
+		names, err := rawRule.Queues.compile(path + ".queues")
+		if err != nil {
+			return nil, err
+		}
+		properties, err := rawRule.Queues.compileProperties(path + ".queues")
+		if err != nil {
+			return nil, err
+		}
+		rule.Queues = &EntityRule{Names: names, QueueFilter: properties}
+	}
+	if rawRule.Exchanges != nil {
+		names, err := rawRule.Exchanges.compile(path + ".exchanges")
+		if err != nil {
+			return nil, err
+		}
+		rule.Exchanges = &EntityRule{Names: names, ExchangeFilter: rawRule.Exchanges.Properties}
+	}
+	return rule, nil
+}
+
+func (f *nameFilters) compile(path string) (NameFilter, error) {
+	includeRegexes, err := compileRegexes(path+".include_regexes", f.IncludeRegexes)
+	if err != nil {
+		return NameFilter{}, err
+	}
+	excludeRegexes, err := compileRegexes(path+".exclude_regexes", f.ExcludeRegexes)
+	if err != nil {
+		return NameFilter{}, err
+	}
+	return NameFilter{Include: f.Include, IncludeRegexes: includeRegexes, Exclude: f.Exclude, ExcludeRegexes: excludeRegexes}, nil
+}
+
+func (f *queueFilters) compileProperties(path string) (*QueueFilter, error) {
+	if f.Properties == nil {
+		return nil, nil
+	}
+	if err := f.Properties.validate(); err != nil {
+		return nil, fmt.Errorf("%s.properties: %v", path, err)
+	}
+	return f.Properties, nil
+}
+
+func compileRegexes(path string, values []string) ([]*regexp.Regexp, error) {
+	var regexes []*regexp.Regexp
+	for i, value := range values {
+		regex, err := regexp.Compile(value)
+		if err != nil {
+			return nil, fmt.Errorf("%s[%d]: %v", path, i, err)
+		}
+		regexes = append(regexes, regex)
+	}
+	return regexes, nil
+}
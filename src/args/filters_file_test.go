@@ -0,0 +1,69 @@
+package args
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/newrelic/nri-rabbitmq/src/data/consts"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSetGlobalArgs_FiltersConfigPath(t *testing.T) {
+	require.NoError(t, SetGlobalArgs(ArgumentList{FiltersConfigPath: filepath.Join("testdata", "filters.yml")}))
+
+	assert.False(t, GlobalArgs.IncludeEntity("amq.gen-1", consts.QueueType, "/"))
+	assert.True(t, GlobalArgs.IncludeEntity("billing", consts.QueueType, "/"))
+	assert.False(t, GlobalArgs.IncludeEntity("amq.direct", consts.ExchangeType, "/"))
+	assert.False(t, GlobalArgs.IncludeEntity("test", consts.VhostType, "test"))
+	assert.False(t, GlobalArgs.IncludeEntity("rabbit@old", consts.NodeType, ""))
+	if assert.NotNil(t, GlobalArgs.QueueFilterFor("/").Exclusive) {
+		assert.False(t, *GlobalArgs.QueueFilterFor("/").Exclusive)
+	}
+	assert.Equal(t, []string{"x-delayed-message"}, GlobalArgs.ExchangeFilterFor("/").ExcludeTypes)
+
+	// the rules replace the filters of their entity types in the vhosts they match
+	assert.True(t, GlobalArgs.IncludeEntity("orders.eu", consts.QueueType, "/orders"))
+	assert.False(t, GlobalArgs.IncludeEntity("billing", consts.QueueType, "/orders"))
+	assert.Equal(t, []string{QueueTypeQuorum}, GlobalArgs.QueueFilterFor("/orders").Types)
+	assert.True(t, GlobalArgs.IncludeEntity("events", consts.ExchangeType, "tenant-1"))
+	assert.False(t, GlobalArgs.IncludeEntity("amq.direct", consts.ExchangeType, "tenant-1"))
+	assert.False(t, GlobalArgs.IncludeEntity("other", consts.ExchangeType, "tenant-1"))
+	assert.Nil(t, GlobalArgs.ExchangeFilterFor("tenant-1"))
+	assert.False(t, GlobalArgs.IncludeEntity("amq.direct", consts.ExchangeType, "/orders"), "the rule only replaces the queue filters")
+	assert.NotNil(t, GlobalArgs.QueueFilterFor("tenant-1"))
+}
+
+func TestSetGlobalArgs_FiltersConfigPath_Errors(t *testing.T) {
+	err := SetGlobalArgs(ArgumentList{FiltersConfigPath: filepath.Join("testdata", "filters.yml"), QueuesExclude: `["reply"]`})
+	assert.EqualError(t, err, "the filters can't be set both in FiltersConfigPath and in the arguments")
+
+	err = SetGlobalArgs(ArgumentList{FiltersConfigPath: filepath.Join("testdata", "missing.yml")})
+	assert.Error(t, err)
+
+	tests := map[string]string{
+		"queues:\n  exclude_regexes: ['^ok', '(invalid']\n":                           "queues.exclude_regexes[1]: error parsing regexp",
+		"queues:\n  properties:\n    types: [lazy]\n":                                 "queues.properties: invalid queue type [lazy]",
+		"queues:\n  excludes: [a]\n":                                                  "field excludes not found",
+		"nodes:\n  include_regexes: ['(a']\n":                                         "nodes.include_regexes[0]: error parsing regexp",
+		"vhost_rules:\n  - queues:\n      exclude: [a]\n":                             "vhost_rules[0]: exactly one of vhost or vhost_regex must be set",
+		"vhost_rules:\n  - vhost: a\n":                                                "vhost_rules[0]: queues or exchanges must be set",
+		"vhost_rules:\n  - vhost_regex: '(a'\n    queues: {}\n":                       "vhost_rules[0].vhost_regex: error parsing regexp",
+		"vhost_rules:\n  - vhost: a\n    exchanges:\n      include_regexes: ['(a']\n": "vhost_rules[0].exchanges.include_regexes[0]",
+	}
+	for content, expected := range tests {
+		path := filepath.Join(t.TempDir(), "filters.yml")
+		require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+		err := SetGlobalArgs(ArgumentList{FiltersConfigPath: path})
+		if assert.Error(t, err, content) {
+			assert.Contains(t, err.Error(), path+": ")
+			assert.Contains(t, err.Error(), expected)
+		}
+	}
+
+	path := filepath.Join(t.TempDir(), "filters.yml")
+	require.NoError(t, os.WriteFile(path, nil, 0o600))
+	assert.NoError(t, SetGlobalArgs(ArgumentList{FiltersConfigPath: path}), "an empty file sets no filters")
+}
@@ -0,0 +1,77 @@
+package args
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// The queue types reported by the Management API, queues without a type are classic queues
+const (
+	QueueTypeClassic = "classic"
+	QueueTypeQuorum  = "quorum"
+	QueueTypeStream  = "stream"
+)
+
+// QueueFilter filters the queues by their properties, the unset properties are not checked,
+// e.g. {"exclusive": false, "auto_delete": false, "types": ["quorum"]}
+type QueueFilter struct {
+	Exclusive  *bool `json:"exclusive" yaml:"exclusive"`
+	AutoDelete *bool `json:"auto_delete" yaml:"auto_delete"`
+	Durable    *bool `json:"durable" yaml:"durable"`
+	// Types and ExcludeTypes are lists of queue types: classic, quorum or stream
+	Types        []string `json:"types" yaml:"types"`
+	ExcludeTypes []string `json:"exclude_types" yaml:"exclude_types"`
+	// Arguments are the arguments the queues must be declared with, a null value matches any value of the argument
+	Arguments map[string]interface{} `json:"arguments" yaml:"arguments"`
+	// ExcludeArguments are the names of the arguments the queues must not be declared with
+	ExcludeArguments []string `json:"exclude_arguments" yaml:"exclude_arguments"`
+}
+
+// ExchangeFilter filters the exchanges by their properties, the unset properties are not checked,
+// e.g. {"exclude_types": ["x-delayed-message"]}
+type ExchangeFilter struct {
+	AutoDelete *bool `json:"auto_delete" yaml:"auto_delete"`
+	Durable    *bool `json:"durable" yaml:"durable"`
+	Internal   *bool `json:"internal" yaml:"internal"`
+	// Types and ExcludeTypes are lists of exchange types, e.g. direct, topic or x-delayed-message
+	Types            []string               `json:"types" yaml:"types"`
+	ExcludeTypes     []string               `json:"exclude_types" yaml:"exclude_types"`
+	Arguments        map[string]interface{} `json:"arguments" yaml:"arguments"`
+	ExcludeArguments []string               `json:"exclude_arguments" yaml:"exclude_arguments"`
+}
+
+func parseQueueFilter(argValue string) (*QueueFilter, error) {
+	if argValue == "" {
+		return nil, nil
+	}
+	filter := &QueueFilter{}
+	if err := json.Unmarshal([]byte(argValue), filter); err != nil {
+		return nil, err
+	}
+	if err := filter.validate(); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
+
+func (filter *QueueFilter) validate() error {
+	for _, queueType := range append(append([]string{}, filter.Types...), filter.ExcludeTypes...) {
+		switch queueType {
+		case QueueTypeClassic, QueueTypeQuorum, QueueTypeStream:
+		default:
+			return fmt.Errorf("invalid queue type [%s], it must be %s, %s or %s", queueType, QueueTypeClassic, QueueTypeQuorum, QueueTypeStream)
+		}
+	}
+	return nil
+}
+
+func parseExchangeFilter(argValue string) (*ExchangeFilter, error) {
+	if argValue == "" {
+		return nil, nil
+	}
+	filter := &ExchangeFilter{}
+	if err := json.Unmarshal([]byte(argValue), filter); err != nil {
+		return nil, err
+	}
+	return filter, nil
+}
@@ -0,0 +1,44 @@
+package args
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseQueueFilter(t *testing.T) {
+	filter, err := parseQueueFilter(`{"exclusive": false, "types": ["quorum"], "arguments": {"x-max-length": 1000}}`)
+	require.NoError(t, err)
+	if assert.NotNil(t, filter.Exclusive) {
+		assert.False(t, *filter.Exclusive)
+	}
+	assert.Nil(t, filter.AutoDelete)
+	assert.Equal(t, []string{QueueTypeQuorum}, filter.Types)
+	assert.Equal(t, map[string]interface{}{"x-max-length": float64(1000)}, filter.Arguments)
+
+	filter, err = parseQueueFilter("")
+	assert.NoError(t, err)
+	assert.Nil(t, filter)
+
+	_, err = parseQueueFilter(`{"exclude_types": ["lazy"]}`)
+	assert.Error(t, err)
+	_, err = parseQueueFilter(`[]`)
+	assert.Error(t, err)
+}
+
+func Test_parseExchangeFilter(t *testing.T) {
+	filter, err := parseExchangeFilter(`{"internal": false, "exclude_types": ["x-delayed-message"]}`)
+	require.NoError(t, err)
+	if assert.NotNil(t, filter.Internal) {
+		assert.False(t, *filter.Internal)
+	}
+	assert.Equal(t, []string{"x-delayed-message"}, filter.ExcludeTypes)
+
+	filter, err = parseExchangeFilter("")
+	assert.NoError(t, err)
+	assert.Nil(t, filter)
+
+	_, err = parseExchangeFilter(`{"internal": "no"}`)
+	assert.Error(t, err)
+}
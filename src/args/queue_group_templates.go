@@ -0,0 +1,90 @@
+package args
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// QueueGroupTemplate maps the queue names matching Regex to a group name, written with {capture} placeholders
+// referring to the named or numbered captures of Regex, e.g. {"regex": "^orders\\.(?P<tenant>[^.]+)\\.shard-\\d+$", "group": "orders.{tenant}"}
+type QueueGroupTemplate struct {
+	Regex         string         `json:"regex"`
+	Group         string         `json:"group"`
+	CompiledRegex *regexp.Regexp `json:"-"`
+	// template is Group in the syntax of regexp.Regexp.ExpandString
+	template string
+}
+
+// placeholderRegex matches the {capture} placeholders of a group template
+var placeholderRegex = regexp.MustCompile(`\{(\w+)\}`)
+
+// QueueGroup returns the group name of the queue and its named captures, from the first template matching the queue name
+func (args *RabbitMQArguments) QueueGroup(queueName string) (string, map[string]string, bool) {
+	for _, template := range args.QueueGroupTemplates {
+		if group, captures, ok := template.apply(queueName); ok {
+			return group, captures, true
+		}
+	}
+	return "", nil, false
+}
+
+func (t *QueueGroupTemplate) apply(queueName string) (string, map[string]string, bool) {
+	match := t.CompiledRegex.FindStringSubmatchIndex(queueName)
+	if match == nil {
+		return "", nil, false
+	}
+	group := string(t.CompiledRegex.ExpandString(nil, t.template, queueName, match))
+	captures := make(map[string]string)
+	for i, name := range t.CompiledRegex.SubexpNames() {
+		if name != "" && match[2*i] >= 0 {
+			captures[name] = queueName[match[2*i]:match[2*i+1]]
+		}
+	}
+	return group, captures, true
+}
+
+// parseQueueGroupTemplates parses a JSON array of templates, checking their placeholders refer to captures of their regexes
+func parseQueueGroupTemplates(argValue string) ([]*QueueGroupTemplate, error) {
+	if argValue == "" {
+		return nil, nil
+	}
+	var templates []*QueueGroupTemplate
+	if err := json.Unmarshal([]byte(argValue), &templates); err != nil {
+		return nil, err
+	}
+	for _, t := range templates {
+		if t.Group == "" {
+			return nil, fmt.Errorf("queue group template for regex [%s] has no group", t.Regex)
+		}
+		regex, err := regexp.Compile(t.Regex)
+		if err != nil {
+			return nil, err
+		}
+		t.CompiledRegex = regex
+
+		// a $ in the group is literal text, while ExpandString would read it as a reference to a capture
+		literalGroup := strings.ReplaceAll(t.Group, "$", "$$")
+		var placeholderErr error
+		t.template = placeholderRegex.ReplaceAllStringFunc(literalGroup, func(placeholder string) string {
+			capture := placeholder[1 : len(placeholder)-1]
+			if !hasCapture(regex, capture) {
+				placeholderErr = fmt.Errorf("queue group template [%s] refers to capture [%s] missing in regex [%s]", t.Group, capture, t.Regex)
+			}
+			return "${" + capture + "}"
+		})
+		if placeholderErr != nil {
+			return nil, placeholderErr
+		}
+	}
+	return templates, nil
+}
+
+func hasCapture(regex *regexp.Regexp, capture string) bool {
+	if index, err := strconv.Atoi(capture); err == nil {
+		return index <= regex.NumSubexp()
+	}
+	return regex.SubexpIndex(capture) > 0
+}
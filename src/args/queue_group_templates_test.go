@@ -0,0 +1,71 @@
+package args
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseQueueGroupTemplates(t *testing.T) {
+	templates, err := parseQueueGroupTemplates(`[
+		{"regex": "^orders\\.(?P<tenant>[^.]+)\\.shard-(?P<shard>\\d+)$", "group": "orders.{tenant}"},
+		{"regex": "^(\\w+)\\.dlq$", "group": "{1}.dlq"},
+		{"regex": "^orders\\.", "group": "orders"}
+	]`)
+	require.NoError(t, err)
+	require.Len(t, templates, 3)
+	args := RabbitMQArguments{QueueGroupTemplates: templates}
+
+	group, captures, ok := args.QueueGroup("orders.tenant-123.shard-4")
+	assert.True(t, ok)
+	assert.Equal(t, "orders.tenant-123", group)
+	assert.Equal(t, map[string]string{"tenant": "tenant-123", "shard": "4"}, captures)
+
+	group, captures, ok = args.QueueGroup("billing.dlq")
+	assert.True(t, ok)
+	assert.Equal(t, "billing.dlq", group)
+	assert.Empty(t, captures)
+
+	group, _, ok = args.QueueGroup("orders.eu")
+	assert.True(t, ok, "the first matching template is used")
+	assert.Equal(t, "orders", group)
+
+	_, _, ok = args.QueueGroup("billing")
+	assert.False(t, ok)
+
+	templates, err = parseQueueGroupTemplates("")
+	assert.NoError(t, err)
+	assert.Nil(t, templates)
+}
+
+func Test_parseQueueGroupTemplates_LiteralDollar(t *testing.T) {
+	templates, err := parseQueueGroupTemplates(`[
+		{"regex": "^orders\\.(?P<tenant>[^.]+)\\.shard-\\d+$", "group": "cost$1.{tenant}"},
+		{"regex": "^(\\w+)\\.dlq$", "group": "$$tenant.{1}$"}
+	]`)
+	require.NoError(t, err)
+	args := RabbitMQArguments{QueueGroupTemplates: templates}
+
+	group, _, ok := args.QueueGroup("orders.acme.shard-1")
+	assert.True(t, ok)
+	assert.Equal(t, "cost$1.acme", group, "the $ outside the placeholders is kept as is")
+
+	group, _, ok = args.QueueGroup("billing.dlq")
+	assert.True(t, ok)
+	assert.Equal(t, "$$tenant.billing$", group)
+}
+
+func Test_parseQueueGroupTemplates_Errors(t *testing.T) {
+	invalid := []string{
+		`[,]`,
+		`[{"regex": "^orders"}]`,
+		`[{"regex": "(orders", "group": "orders"}]`,
+		`[{"regex": "^orders\\.(?P<tenant>[^.]+)", "group": "orders.{tenant}.{shard}"}]`,
+		`[{"regex": "^orders\\.([^.]+)", "group": "orders.{2}"}]`,
+	}
+	for _, argValue := range invalid {
+		_, err := parseQueueGroupTemplates(argValue)
+		assert.Error(t, err, argValue)
+	}
+}
@@ -13,6 +13,12 @@ func TestSetGlobalArgs_Default(t *testing.T) {
 	argList := ArgumentList{}
 	err := SetGlobalArgs(argList)
 	assert.NoError(t, err, "err should be nil")
+	assert.Equal(t, CountersAsGauge, GlobalArgs.CountersSourceType)
+	assert.Equal(t, QueuesLimitDrop, GlobalArgs.QueuesLimitMode)
+	assert.Equal(t, RankByMessages, GlobalArgs.QueuesRankBy)
+	assert.Equal(t, QueuesAggregationNone, GlobalArgs.QueuesAggregation)
+	assert.Equal(t, ClusterEntitiesAlways, GlobalArgs.ClusterEntities)
+	assert.True(t, GlobalArgs.ReportsClusterEntities())
 }
 
 func TestSetGlobalArgs_BadArgs(t *testing.T) {
@@ -50,6 +56,46 @@ func TestSetGlobalArgs_BadArgs(t *testing.T) {
 	argList.VhostsRegexes = `["(invalid-group"]`
 	err = SetGlobalArgs(argList)
 	assert.Error(t, err)
+
+	argList.VhostsRegexes = ""
+	argList.RedactKeysRegexes = `["(invalid-group"]`
+	err = SetGlobalArgs(argList)
+	assert.Error(t, err)
+
+	argList.RedactKeysRegexes = ""
+	argList.CountersSourceType = "counter"
+	err = SetGlobalArgs(argList)
+	assert.Error(t, err)
+
+	argList.CountersSourceType = ""
+	argList.QueuesLimitMode = "sample"
+	err = SetGlobalArgs(argList)
+	assert.Error(t, err)
+
+	argList.QueuesLimitMode = ""
+	argList.QueuesRankBy = "name"
+	err = SetGlobalArgs(argList)
+	assert.Error(t, err)
+
+	argList.QueuesRankBy = ""
+	argList.QueuesAggregation = "cluster"
+	err = SetGlobalArgs(argList)
+	assert.Error(t, err)
+
+	argList.QueuesAggregation = ""
+	argList.QueueGroupsRegexes = `["(invalid-group"]`
+	err = SetGlobalArgs(argList)
+	assert.Error(t, err)
+
+	argList.QueueGroupsRegexes = ""
+	argList.ClusterEntities = "leader"
+	err = SetGlobalArgs(argList)
+	assert.Error(t, err)
+
+	argList.ClusterEntities = ""
+	argList.NodesRegexes = `["(invalid-group"]`
+	err = SetGlobalArgs(argList)
+	assert.Error(t, err)
 }
 
 func TestSetGlobalArgs_ValidJson(t *testing.T) {
@@ -105,3 +151,131 @@ func TestRabbitMQArguments_IncludeEntity(t *testing.T) {
 	testArgs = RabbitMQArguments{}
 	assert.True(t, testArgs.IncludeEntity("any", consts.VhostType, "any"))
 }
+
+func TestRabbitMQArguments_IncludeEntity_Exclude(t *testing.T) {
+	testArgs := RabbitMQArguments{
+		QueuesRegexes:        []*regexp.Regexp{regexp.MustCompile("^orders")},
+		QueuesExclude:        []string{"orders.reply"},
+		QueuesExcludeRegexes: []*regexp.Regexp{regexp.MustCompile(`^amq\.gen-`)},
+		ExchangesExclude:     []string{"amq.direct"},
+		VhostsExcludeRegexes: []*regexp.Regexp{regexp.MustCompile("^test")},
+		NodesExclude:         []string{"rabbit@old"},
+	}
+	assert.True(t, testArgs.IncludeEntity("orders.eu", consts.QueueType, "prod"))
+	assert.False(t, testArgs.IncludeEntity("orders.reply", consts.QueueType, "prod"), "excludes take precedence over includes")
+	assert.False(t, testArgs.IncludeEntity("amq.gen-JzTY20BRgKO", consts.QueueType, "prod"))
+	assert.False(t, testArgs.IncludeEntity("billing", consts.QueueType, "prod"))
+
+	assert.True(t, testArgs.IncludeEntity("billing", consts.ExchangeType, "prod"))
+	assert.False(t, testArgs.IncludeEntity("amq.direct", consts.ExchangeType, "prod"))
+
+	assert.False(t, testArgs.IncludeEntity("orders.eu", consts.QueueType, "test1"))
+	assert.False(t, testArgs.IncludeEntity("test1", consts.VhostType, "test1"))
+	assert.True(t, testArgs.IncludeEntity("prod", consts.VhostType, "prod"))
+
+	assert.False(t, testArgs.IncludeEntity("rabbit@old", consts.NodeType, ""))
+	assert.True(t, testArgs.IncludeEntity("rabbit@new", consts.NodeType, ""))
+	assert.True(t, testArgs.IncludeEntity("cluster", consts.ClusterType, ""))
+}
+
+func TestRabbitMQArguments_IncludeEntity_Nodes(t *testing.T) {
+	testArgs := RabbitMQArguments{
+		NodesRegexes: []*regexp.Regexp{regexp.MustCompile("@prod-")},
+		NodesExclude: []string{"rabbit@prod-3"},
+	}
+	assert.True(t, testArgs.IncludeEntity("rabbit@prod-1", consts.NodeType, ""))
+	assert.True(t, testArgs.IncludeEntity("rabbit@prod-2", consts.NodeType, ""))
+	assert.False(t, testArgs.IncludeEntity("rabbit@prod-3", consts.NodeType, ""))
+	assert.False(t, testArgs.IncludeEntity("rabbit@test-1", consts.NodeType, ""))
+
+	testArgs.LocalNodeOnly = true
+	testArgs.LocalNodeName = "rabbit@prod-2"
+	assert.False(t, testArgs.IncludeEntity("rabbit@prod-1", consts.NodeType, ""))
+	assert.True(t, testArgs.IncludeEntity("rabbit@prod-2", consts.NodeType, ""))
+
+	testArgs.LocalNodeName = ""
+	assert.False(t, testArgs.IncludeEntity("rabbit@prod-2", consts.NodeType, ""), "no node is included if the local node is unknown")
+}
+
+func TestRabbitMQArguments_IncludeNodeStatus(t *testing.T) {
+	testArgs := RabbitMQArguments{NodesExclude: []string{"rabbit@prod-3"}, LocalNodeOnly: true, LocalNodeName: "rabbit@prod-2"}
+	assert.False(t, testArgs.IncludeNodeStatus("rabbit@prod-1"))
+	assert.True(t, testArgs.IncludeNodeStatus("rabbit@prod-2"))
+
+	testArgs.ClusterCollector = true
+	assert.True(t, testArgs.IncludeNodeStatus("rabbit@prod-1"), "the cluster collector reports the status of every node")
+	assert.False(t, testArgs.IncludeNodeStatus("rabbit@prod-3"))
+	assert.False(t, testArgs.IncludeEntity("rabbit@prod-1", consts.NodeType, ""), "the other nodes are still not reported")
+}
+
+func TestSetGlobalArgs_Authentication(t *testing.T) {
+	assert.NoError(t, SetGlobalArgs(ArgumentList{BearerToken: "token"}))
+	assert.NoError(t, SetGlobalArgs(ArgumentList{OauthTokenURL: "https://uaa/oauth/token", OauthClientID: "id", OauthClientSecret: "secret"}))
+	assert.Equal(t, "https://uaa/oauth/token", GlobalArgs.OauthTokenURL)
+
+	assert.Error(t, SetGlobalArgs(ArgumentList{BearerToken: "token", BearerTokenFile: "/var/run/token"}))
+	assert.Error(t, SetGlobalArgs(ArgumentList{OauthTokenURL: "https://uaa/oauth/token", OauthClientID: "id"}))
+}
+
+func TestRabbitMQArguments_IncludeQueueNode(t *testing.T) {
+	testArgs := RabbitMQArguments{}
+	assert.True(t, testArgs.IncludeQueueNode("rabbit@a"))
+
+	testArgs = RabbitMQArguments{LocalQueuesOnly: true, LocalNodeName: "rabbit@a"}
+	assert.True(t, testArgs.IncludeQueueNode("rabbit@a"))
+	assert.False(t, testArgs.IncludeQueueNode("rabbit@b"))
+	assert.False(t, testArgs.IncludeQueueNode(""))
+}
+
+func TestRabbitMQArguments_ReportsClusterEntities(t *testing.T) {
+	assert.True(t, (&RabbitMQArguments{}).ReportsClusterEntities())
+	assert.True(t, (&RabbitMQArguments{ClusterEntities: ClusterEntitiesAlways}).ReportsClusterEntities())
+	assert.False(t, (&RabbitMQArguments{ClusterEntities: ClusterEntitiesNever}).ReportsClusterEntities())
+	assert.False(t, (&RabbitMQArguments{ClusterEntities: ClusterEntitiesAuto}).ReportsClusterEntities())
+	assert.True(t, (&RabbitMQArguments{ClusterEntities: ClusterEntitiesAuto, ClusterCollector: true}).ReportsClusterEntities())
+}
+
+func TestSetGlobalArgs_Exclude(t *testing.T) {
+	argList := ArgumentList{
+		QueuesExclude:           `["reply"]`,
+		QueuesExcludeRegexes:    `["^amq\\.gen-"]`,
+		ExchangesExclude:        `["amq.direct"]`,
+		ExchangesExcludeRegexes: `["^amq\\."]`,
+		VhostsExclude:           `["test"]`,
+		VhostsExcludeRegexes:    `["^test"]`,
+		NodesExclude:            `["rabbit@old"]`,
+		NodesExcludeRegexes:     `["@old$"]`,
+	}
+	assert.NoError(t, SetGlobalArgs(argList))
+	assert.Equal(t, []string{"reply"}, GlobalArgs.QueuesExclude)
+	assert.True(t, GlobalArgs.QueuesExcludeRegexes[0].MatchString("amq.gen-1"))
+	assert.Equal(t, []string{"amq.direct"}, GlobalArgs.ExchangesExclude)
+	assert.Len(t, GlobalArgs.ExchangesExcludeRegexes, 1)
+	assert.Equal(t, []string{"test"}, GlobalArgs.VhostsExclude)
+	assert.Len(t, GlobalArgs.VhostsExcludeRegexes, 1)
+	assert.Equal(t, []string{"rabbit@old"}, GlobalArgs.NodesExclude)
+	assert.Len(t, GlobalArgs.NodesExcludeRegexes, 1)
+
+	argList.NodesExcludeRegexes = `["(invalid-group"]`
+	assert.Error(t, SetGlobalArgs(argList))
+	argList.NodesExcludeRegexes = ""
+	argList.QueuesExclude = "invalid"
+	assert.Error(t, SetGlobalArgs(argList))
+}
+
+func TestRabbitMQArguments_IsRedactedKey(t *testing.T) {
+	testArgs := RabbitMQArguments{}
+	assert.True(t, testArgs.IsRedactedKey("default_pass"))
+	assert.True(t, testArgs.IsRedactedKey("management.oauth_client_secret"))
+	assert.True(t, testArgs.IsRedactedKey("ssl_options.key"))
+	assert.False(t, testArgs.IsRedactedKey("ssl_options.keyfile"))
+	assert.False(t, testArgs.IsRedactedKey("default_user"))
+	assert.True(t, testArgs.IsRedactedKey("rabbitmq_auth_backend_ldap.dn_lookup_bind"))
+	assert.True(t, testArgs.IsRedactedKey("rabbitmq_auth_backend_ldap.other_bind"))
+	assert.False(t, testArgs.IsRedactedKey("rabbitmq_auth_backend_ldap.servers"))
+
+	testArgs.RedactKeysRegexes = []*regexp.Regexp{regexp.MustCompile("^default_user$")}
+	assert.True(t, testArgs.IsRedactedKey("default_user"))
+	assert.True(t, testArgs.IsRedactedKey("default_pass"), "the configured regexes are added to the defaults")
+	assert.True(t, testArgs.IsRedactedKey("management.oauth_client_secret"))
+}
@@ -2,6 +2,8 @@ package args
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"regexp"
 
 	"github.com/newrelic/nri-rabbitmq/src/data/consts"
@@ -16,62 +18,217 @@ var GlobalArgs RabbitMQArguments
 // RabbitMQArguments is the fully parsed arguments, converting the JSON string into actual types
 type RabbitMQArguments struct {
 	sdkArgs.DefaultArgumentList
-	Hostname             string
-	Port                 int
-	Username             string
-	Password             string
-	ManagementPathPrefix string
-	CABundleFile         string
-	CABundleDir          string
-	NodeNameOverride     string
-	ConfigPath           string
-	UseSSL               bool
-	Timeout              int
-	DisableEntities      bool
-	QueuesMaxLimit       int
-	Queues               []string
-	QueuesRegexes        []*regexp.Regexp
-	Exchanges            []string
-	ExchangesRegexes     []*regexp.Regexp
-	Vhosts               []string
-	VhostsRegexes        []*regexp.Regexp
-}
-
-// IncludeEntity returns true if the entity should be included; false otherwise
+	Hostname                string
+	Port                    int
+	Username                string
+	Password                string
+	BearerToken             string
+	BearerTokenFile         string
+	OauthTokenURL           string
+	OauthClientID           string
+	OauthClientSecret       string
+	OauthScope              string
+	ManagementPathPrefix    string
+	CABundleFile            string
+	CABundleDir             string
+	NodeNameOverride        string
+	ConfigPath              string
+	EffectiveConfig         bool
+	EffectiveConfigPath     string
+	UseSSL                  bool
+	Timeout                 int
+	DisableEntities         bool
+	QueuesMaxLimit          int
+	Queues                  []string
+	QueuesRegexes           []*regexp.Regexp
+	Exchanges               []string
+	ExchangesRegexes        []*regexp.Regexp
+	Vhosts                  []string
+	VhostsRegexes           []*regexp.Regexp
+	QueuesExclude           []string
+	QueuesExcludeRegexes    []*regexp.Regexp
+	ExchangesExclude        []string
+	ExchangesExcludeRegexes []*regexp.Regexp
+	VhostsExclude           []string
+	VhostsExcludeRegexes    []*regexp.Regexp
+	Nodes                   []string
+	NodesRegexes            []*regexp.Regexp
+	NodesExclude            []string
+	NodesExcludeRegexes     []*regexp.Regexp
+	QueuesFilter            *QueueFilter
+	ExchangesFilter         *ExchangeFilter
+	VhostRules              []*VhostRule
+	LocalNodeOnly           bool
+	ClusterEntities         string
+	LocalQueuesOnly         bool
+	// LocalNodeName is resolved at runtime when LocalNodeOnly or LocalQueuesOnly is set
+	LocalNodeName string
+	// ClusterCollector is resolved at runtime when ClusterEntities is auto or LocalNodeOnly is set
+	ClusterCollector    bool
+	RedactKeysRegexes   []*regexp.Regexp
+	AlertRules          []*AlertRule
+	CountersSourceType  string
+	LegacyCounterGauges bool
+	QueuesLimitMode     string
+	QueuesRankBy        string
+	QueuesAggregation   string
+	QueueGroupsRegexes  []*regexp.Regexp
+	QueueGroupTemplates []*QueueGroupTemplate
+}
+
+// The ClusterEntities values
+const (
+	ClusterEntitiesAlways = "always"
+	ClusterEntitiesNever  = "never"
+	ClusterEntitiesAuto   = "auto"
+)
+
+// The QueuesAggregation values
+const (
+	QueuesAggregationNone  = "none"
+	QueuesAggregationVhost = "vhost"
+	QueuesAggregationOnly  = "only"
+)
+
+// The QueuesLimitMode values
+const (
+	QueuesLimitDrop = "drop"
+	QueuesLimitTop  = "top"
+)
+
+// The QueuesRankBy values
+const (
+	RankByMessages               = "messages"
+	RankByMessagesUnacknowledged = "messages_unacknowledged"
+	RankByPublishRate            = "publish_rate"
+	RankByMemory                 = "memory"
+	RankByConsumerUtilisation    = "consumer_utilisation"
+)
+
+// The source types cumulative counters can be reported as
+const (
+	CountersAsGauge = "gauge"
+	CountersAsRate  = "rate"
+	CountersAsDelta = "delta"
+)
+
+// defaultRedactKeysRegexes are always redacted, along with the keys matching RedactKeysRegexes. The LDAP bind settings
+// hold {UserDN, Password} tuples in advanced.config, so their whole values are redacted.
+var defaultRedactKeysRegexes = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)pass`),
+	regexp.MustCompile(`(?i)secret`),
+	regexp.MustCompile(`(?i)token`),
+	regexp.MustCompile(`(?i)(^|[._])key$`),
+	regexp.MustCompile(`(?i)(^|[._])(dn_lookup|other)_bind$`),
+}
+
+// IncludeEntity returns true if the entity should be included; false otherwise. An entity matching an exclude list is
+// never included, even if it also matches an include list.
 func (args *RabbitMQArguments) IncludeEntity(entityName string, entityType string, vhostName string) bool {
-	if entityType == consts.NodeType {
+	if entityType == consts.ClusterType {
 		return true
 	}
+	if entityType == consts.NodeType {
+		return args.includeNode(entityName)
+	}
 
 	if !args.includeVhost(vhostName) {
 		return false
 	}
 
 	if entityType == consts.QueueType {
+		if rule := args.vhostRule(vhostName); rule != nil && rule.Queues != nil {
+			return rule.Queues.Names.includes(entityName)
+		}
 		return args.includeQueue(entityName)
 	} else if entityType == consts.ExchangeType {
+		if rule := args.vhostRule(vhostName); rule != nil && rule.Exchanges != nil {
+			return rule.Exchanges.Names.includes(entityName)
+		}
 		return args.includeExchange(entityName)
 	} else {
 		return true
 	}
 }
 
+// IsRedactedKey returns true if the values of the inventory key must not be reported
+func (args *RabbitMQArguments) IsRedactedKey(key string) bool {
+	for _, regexes := range [][]*regexp.Regexp{defaultRedactKeysRegexes, args.RedactKeysRegexes} {
+		for _, reg := range regexes {
+			if reg.MatchString(key) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
 // includeExchange returns true if exchange should be included; false otherwise
 func (args *RabbitMQArguments) includeExchange(exchangeName string) bool {
-	return includeName(exchangeName, args.Exchanges, args.ExchangesRegexes)
+	return !matchesName(exchangeName, args.ExchangesExclude, args.ExchangesExcludeRegexes) &&
+		includeName(exchangeName, args.Exchanges, args.ExchangesRegexes)
 }
 
 // includeQueue returns true if queue should be included; false otherwise
 func (args *RabbitMQArguments) includeQueue(queueName string) bool {
-	return includeName(queueName, args.Queues, args.QueuesRegexes)
+	return !matchesName(queueName, args.QueuesExclude, args.QueuesExcludeRegexes) &&
+		includeName(queueName, args.Queues, args.QueuesRegexes)
+}
+
+// includeNode returns true if node should be included; false otherwise
+func (args *RabbitMQArguments) includeNode(nodeName string) bool {
+	if args.LocalNodeOnly && nodeName != args.LocalNodeName {
+		return false
+	}
+	return args.includeNodeName(nodeName)
+}
+
+func (args *RabbitMQArguments) includeNodeName(nodeName string) bool {
+	return !matchesName(nodeName, args.NodesExclude, args.NodesExcludeRegexes) &&
+		includeName(nodeName, args.Nodes, args.NodesRegexes)
+}
+
+// IncludeNodeStatus returns true if the running status of the node should be reported. With LocalNodeOnly, the
+// cluster collector reports it for every node, as a node that is down has no instance of its own reporting it.
+func (args *RabbitMQArguments) IncludeNodeStatus(nodeName string) bool {
+	if args.LocalNodeOnly && args.ClusterCollector {
+		return args.includeNodeName(nodeName)
+	}
+	return args.includeNode(nodeName)
+}
+
+// IncludeQueueNode returns true if the queues of the node should be included; false otherwise
+func (args *RabbitMQArguments) IncludeQueueNode(nodeName string) bool {
+	return !args.LocalQueuesOnly || nodeName == args.LocalNodeName
+}
+
+// ReportsClusterEntities returns true if this instance reports the entities shared by all the nodes of the cluster
+func (args *RabbitMQArguments) ReportsClusterEntities() bool {
+	switch args.ClusterEntities {
+	case ClusterEntitiesNever:
+		return false
+	case ClusterEntitiesAuto:
+		return args.ClusterCollector
+	}
+	return true
 }
 
 // includeVhost returns true if vhost should be included; false otherwise
 func (args *RabbitMQArguments) includeVhost(vhostName string) bool {
-	return includeName(vhostName, args.Vhosts, args.VhostsRegexes)
+	return !matchesName(vhostName, args.VhostsExclude, args.VhostsExcludeRegexes) &&
+		includeName(vhostName, args.Vhosts, args.VhostsRegexes)
 }
 
+// includeName returns true if the name is in the allow-lists, or if there are none
 func includeName(itemName string, names []string, namesRegex []*regexp.Regexp) bool {
+	if len(names) == 0 && len(namesRegex) == 0 {
+		return true
+	}
+	return matchesName(itemName, names, namesRegex)
+}
+
+// matchesName returns true if the name is one of names or matches one of namesRegex
+func matchesName(itemName string, names []string, namesRegex []*regexp.Regexp) bool {
 	for _, name := range names {
 		if name == itemName {
 			return true
@@ -82,10 +239,7 @@ func includeName(itemName string, names []string, namesRegex []*regexp.Regexp) b
 			return true
 		}
 	}
-	if len(names) > 0 || len(namesRegex) > 0 {
-		return false
-	}
-	return true
+	return false
 }
 
 // SetGlobalArgs validates the arguments in ArgumentList and sets GlobalArgs to the result
@@ -95,17 +249,85 @@ func SetGlobalArgs(args ArgumentList) error {
 		CABundleDir:          args.CABundleDir,
 		CABundleFile:         args.CABundleFile,
 		ConfigPath:           args.ConfigPath,
+		EffectiveConfig:      args.EffectiveConfig,
+		EffectiveConfigPath:  args.EffectiveConfigPath,
 		DefaultArgumentList:  args.DefaultArgumentList,
 		Hostname:             args.Hostname,
 		NodeNameOverride:     args.NodeNameOverride,
 		Password:             args.Password,
+		BearerToken:          args.BearerToken,
+		BearerTokenFile:      args.BearerTokenFile,
+		OauthTokenURL:        args.OauthTokenURL,
+		OauthClientID:        args.OauthClientID,
+		OauthClientSecret:    args.OauthClientSecret,
+		OauthScope:           args.OauthScope,
 		Port:                 args.Port,
 		Username:             args.Username,
 		UseSSL:               args.UseSSL,
 		Timeout:              args.Timeout,
 		DisableEntities:      args.DisableEntities,
 		QueuesMaxLimit:       args.QueuesMaxLimit,
+		CountersSourceType:   args.CountersSourceType,
+		LegacyCounterGauges:  args.LegacyCounterGauges,
+		QueuesLimitMode:      args.QueuesLimitMode,
+		QueuesRankBy:         args.QueuesRankBy,
+		QueuesAggregation:    args.QueuesAggregation,
+		LocalNodeOnly:        args.LocalNodeOnly,
+		LocalQueuesOnly:      args.LocalQueuesOnly,
+		ClusterEntities:      args.ClusterEntities,
+	}
+	if err := validateAuthentication(&rabbitArgs); err != nil {
+		log.Error("Error parsing arguments [Authentication]: %v", err)
+		return err
+	}
+
+	switch rabbitArgs.CountersSourceType {
+	case CountersAsGauge, CountersAsRate, CountersAsDelta:
+	case "":
+		rabbitArgs.CountersSourceType = CountersAsGauge
+	default:
+		err := fmt.Errorf("invalid counters source type [%s], it must be %s, %s or %s", rabbitArgs.CountersSourceType, CountersAsGauge, CountersAsRate, CountersAsDelta)
+		log.Error("Error parsing arguments [CountersSourceType]: %v", err)
+		return err
+	}
+
+	switch rabbitArgs.QueuesLimitMode {
+	case QueuesLimitDrop, QueuesLimitTop:
+	case "":
+		rabbitArgs.QueuesLimitMode = QueuesLimitDrop
+	default:
+		err := fmt.Errorf("invalid queues limit mode [%s], it must be %s or %s", rabbitArgs.QueuesLimitMode, QueuesLimitDrop, QueuesLimitTop)
+		log.Error("Error parsing arguments [QueuesLimitMode]: %v", err)
+		return err
 	}
+	switch rabbitArgs.QueuesRankBy {
+	case RankByMessages, RankByMessagesUnacknowledged, RankByPublishRate, RankByMemory, RankByConsumerUtilisation:
+	case "":
+		rabbitArgs.QueuesRankBy = RankByMessages
+	default:
+		err := fmt.Errorf("invalid queues ranking [%s]", rabbitArgs.QueuesRankBy)
+		log.Error("Error parsing arguments [QueuesRankBy]: %v", err)
+		return err
+	}
+	switch rabbitArgs.QueuesAggregation {
+	case QueuesAggregationNone, QueuesAggregationVhost, QueuesAggregationOnly:
+	case "":
+		rabbitArgs.QueuesAggregation = QueuesAggregationNone
+	default:
+		err := fmt.Errorf("invalid queues aggregation [%s], it must be %s, %s or %s", rabbitArgs.QueuesAggregation, QueuesAggregationNone, QueuesAggregationVhost, QueuesAggregationOnly)
+		log.Error("Error parsing arguments [QueuesAggregation]: %v", err)
+		return err
+	}
+	switch rabbitArgs.ClusterEntities {
+	case ClusterEntitiesAlways, ClusterEntitiesNever, ClusterEntitiesAuto:
+	case "":
+		rabbitArgs.ClusterEntities = ClusterEntitiesAlways
+	default:
+		err := fmt.Errorf("invalid cluster entities [%s], it must be %s, %s or %s", rabbitArgs.ClusterEntities, ClusterEntitiesAlways, ClusterEntitiesNever, ClusterEntitiesAuto)
+		log.Error("Error parsing arguments [ClusterEntities]: %v", err)
+		return err
+	}
+
 	var err error
 	if err = parseStrings(args.Exchanges, &rabbitArgs.Exchanges); err != nil {
 		log.Error("Error parsing arguments [Exchanges]: %v", err)
@@ -119,6 +341,46 @@ func SetGlobalArgs(args ArgumentList) error {
 		log.Error("Error parsing arguments [Vhosts]: %v", err)
 		return err
 	}
+	if err = parseStrings(args.QueuesExclude, &rabbitArgs.QueuesExclude); err != nil {
+		log.Error("Error parsing arguments [QueuesExclude]: %v", err)
+		return err
+	}
+	if rabbitArgs.QueuesExcludeRegexes, err = parseRegexes(args.QueuesExcludeRegexes); err != nil {
+		log.Error("Error parsing arguments [QueuesExcludeRegexes]: %v", err)
+		return err
+	}
+	if err = parseStrings(args.ExchangesExclude, &rabbitArgs.ExchangesExclude); err != nil {
+		log.Error("Error parsing arguments [ExchangesExclude]: %v", err)
+		return err
+	}
+	if rabbitArgs.ExchangesExcludeRegexes, err = parseRegexes(args.ExchangesExcludeRegexes); err != nil {
+		log.Error("Error parsing arguments [ExchangesExcludeRegexes]: %v", err)
+		return err
+	}
+	if err = parseStrings(args.VhostsExclude, &rabbitArgs.VhostsExclude); err != nil {
+		log.Error("Error parsing arguments [VhostsExclude]: %v", err)
+		return err
+	}
+	if rabbitArgs.VhostsExcludeRegexes, err = parseRegexes(args.VhostsExcludeRegexes); err != nil {
+		log.Error("Error parsing arguments [VhostsExcludeRegexes]: %v", err)
+		return err
+	}
+	if err = parseStrings(args.Nodes, &rabbitArgs.Nodes); err != nil {
+		log.Error("Error parsing arguments [Nodes]: %v", err)
+		return err
+	}
+	if rabbitArgs.NodesRegexes, err = parseRegexes(args.NodesRegexes); err != nil {
+		log.Error("Error parsing arguments [NodesRegexes]: %v", err)
+		return err
+	}
+	if err = parseStrings(args.NodesExclude, &rabbitArgs.NodesExclude); err != nil {
+		log.Error("Error parsing arguments [NodesExclude]: %v", err)
+		return err
+	}
+	if rabbitArgs.NodesExcludeRegexes, err = parseRegexes(args.NodesExcludeRegexes); err != nil {
+		log.Error("Error parsing arguments [NodesExcludeRegexes]: %v", err)
+		return err
+	}
 
 	if rabbitArgs.ExchangesRegexes, err = parseRegexes(args.ExchangesRegexes); err != nil {
 		log.Error("Error parsing arguments [ExchangesRegexes]: %v", err)
@@ -132,10 +394,77 @@ func SetGlobalArgs(args ArgumentList) error {
 		log.Error("Error parsing arguments [VhostsRegexes]: %v", err)
 		return err
 	}
+	if rabbitArgs.RedactKeysRegexes, err = parseRegexes(args.RedactKeysRegexes); err != nil {
+		log.Error("Error parsing arguments [RedactKeysRegexes]: %v", err)
+		return err
+	}
+	if rabbitArgs.QueueGroupsRegexes, err = parseRegexes(args.QueueGroupsRegexes); err != nil {
+		log.Error("Error parsing arguments [QueueGroupsRegexes]: %v", err)
+		return err
+	}
+	if rabbitArgs.QueueGroupTemplates, err = parseQueueGroupTemplates(args.QueueGroupTemplates); err != nil {
+		log.Error("Error parsing arguments [QueueGroupTemplates]: %v", err)
+		return err
+	}
+	if rabbitArgs.QueuesFilter, err = parseQueueFilter(args.QueuesFilter); err != nil {
+		log.Error("Error parsing arguments [QueuesFilter]: %v", err)
+		return err
+	}
+	if rabbitArgs.ExchangesFilter, err = parseExchangeFilter(args.ExchangesFilter); err != nil {
+		log.Error("Error parsing arguments [ExchangesFilter]: %v", err)
+		return err
+	}
+	if args.FiltersConfigPath != "" {
+		if hasFilterArguments(args) {
+			err = errors.New("the filters can't be set both in FiltersConfigPath and in the arguments")
+			log.Error("Error parsing arguments [FiltersConfigPath]: %v", err)
+			return err
+		}
+		if err = loadFiltersFile(args.FiltersConfigPath, &rabbitArgs); err != nil {
+			log.Error("Error parsing arguments [FiltersConfigPath]: %v", err)
+			return err
+		}
+	}
+	if rabbitArgs.AlertRules, err = parseAlertRules(args.AlertRules); err != nil {
+		log.Error("Error parsing arguments [AlertRules]: %v", err)
+		return err
+	}
 	GlobalArgs = rabbitArgs
 	return nil
 }
 
+// validateAuthentication checks that a single bearer token source is configured, with the credentials it needs
+func validateAuthentication(rabbitArgs *RabbitMQArguments) error {
+	sources := 0
+	for _, value := range []string{rabbitArgs.BearerToken, rabbitArgs.BearerTokenFile, rabbitArgs.OauthTokenURL} {
+		if value != "" {
+			sources++
+		}
+	}
+	if sources > 1 {
+		return errors.New("only one of BearerToken, BearerTokenFile or OauthTokenURL can be set")
+	}
+	if rabbitArgs.OauthTokenURL != "" && (rabbitArgs.OauthClientID == "" || rabbitArgs.OauthClientSecret == "") {
+		return errors.New("OauthClientID and OauthClientSecret are required by OauthTokenURL")
+	}
+	return nil
+}
+
+// hasFilterArguments returns true if any of the arguments replaced by the filters file is set
+func hasFilterArguments(args ArgumentList) bool {
+	for _, value := range []string{
+		args.Queues, args.QueuesRegexes, args.QueuesExclude, args.QueuesExcludeRegexes, args.QueuesFilter,
+		args.Exchanges, args.ExchangesRegexes, args.ExchangesExclude, args.ExchangesExcludeRegexes, args.ExchangesFilter,
+		args.Vhosts, args.VhostsRegexes, args.VhostsExclude, args.VhostsExcludeRegexes,
+		args.Nodes, args.NodesRegexes, args.NodesExclude, args.NodesExcludeRegexes,
+	} {
+		if value != "" {
+			return true
+		}
+	}
+	return false
+}
+
 func parseStrings(argValue string, value *[]string) error {
 	if argValue != "" {
 		return json.Unmarshal([]byte(argValue), value)
@@ -0,0 +1,116 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/newrelic/nri-rabbitmq/src/args"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/log"
+)
+
+// bearerToken is resolved on the first request, the integration runs once per interval so the token file is
+// re-read and a new token is requested from the token endpoint on every run
+var bearerToken string
+
+// setAuthorization sets the bearer token of the request if one is configured, the basic auth credentials otherwise
+func setAuthorization(req *http.Request) error {
+	token, err := getBearerToken()
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+	req.SetBasicAuth(args.GlobalArgs.Username, args.GlobalArgs.Password)
+	return nil
+}
+
+// getBearerToken returns the token from BearerToken, BearerTokenFile or the client credentials flow, or an empty
+// string if none of them is configured
+func getBearerToken() (string, error) {
+	if bearerToken != "" {
+		return bearerToken, nil
+	}
+	var token string
+	var err error
+	switch {
+	case args.GlobalArgs.BearerToken != "":
+		token = args.GlobalArgs.BearerToken
+	case args.GlobalArgs.BearerTokenFile != "":
+		token, err = readTokenFile(args.GlobalArgs.BearerTokenFile)
+	case args.GlobalArgs.OauthTokenURL != "":
+		token, err = requestClientCredentialsToken()
+	}
+	if err != nil {
+		return "", err
+	}
+	bearerToken = token
+	return token, nil
+}
+
+func readTokenFile(path string) (string, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("error reading bearer token file: %v", err)
+	}
+	token := strings.TrimSpace(string(content))
+	if token == "" {
+		return "", fmt.Errorf("bearer token file [%s] is empty", path)
+	}
+	return token, nil
+}
+
+// tokenResponse is the response of the token endpoint, as defined in RFC 6749 section 5.1
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+}
+
+// requestClientCredentialsToken requests a token to OauthTokenURL with the client credentials grant
+func requestClientCredentialsToken() (string, error) {
+	ensureClient()
+	form := url.Values{"grant_type": {"client_credentials"}}
+	if args.GlobalArgs.OauthScope != "" {
+		form.Set("scope", args.GlobalArgs.OauthScope)
+	}
+	req, err := http.NewRequest(http.MethodPost, args.GlobalArgs.OauthTokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	// the client credentials are form encoded before being used as basic auth, as RFC 6749 section 2.3.1 requires
+	req.SetBasicAuth(url.QueryEscape(args.GlobalArgs.OauthClientID), url.QueryEscape(args.GlobalArgs.OauthClientSecret))
+
+	resp, err := defaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("error requesting OAuth token: %v", err)
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Error("Error closing response body: %v", err)
+		}
+	}()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected http response from OAuth token endpoint [%s]: %s", req.URL, resp.Status)
+	}
+	var token tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("error decoding OAuth token response: %v", err)
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("the OAuth token response has no access_token")
+	}
+	if token.TokenType != "" && !strings.EqualFold(token.TokenType, "bearer") {
+		return "", fmt.Errorf("unsupported OAuth token type [%s]", token.TokenType)
+	}
+	log.Debug("OAuth token obtained from [%s], it expires in %d seconds", req.URL, token.ExpiresIn)
+	return token.AccessToken, nil
+}
@@ -0,0 +1,142 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/newrelic/nri-rabbitmq/src/args"
+	"github.com/newrelic/nri-rabbitmq/src/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func resetAuth(t *testing.T, rabbitArgs args.RabbitMQArguments) {
+	defaultClient = nil
+	bearerToken = ""
+	args.GlobalArgs = rabbitArgs
+	t.Cleanup(func() {
+		bearerToken = ""
+	})
+}
+
+func Test_createRequest_BasicAuth(t *testing.T) {
+	resetAuth(t, args.RabbitMQArguments{Hostname: "localhost", Port: 15672, Username: "user", Password: "pass"})
+	req, err := createRequest(NodesEndpoint)
+	require.NoError(t, err)
+	username, password, ok := req.BasicAuth()
+	assert.True(t, ok)
+	assert.Equal(t, "user", username)
+	assert.Equal(t, "pass", password)
+}
+
+func Test_createRequest_BearerToken(t *testing.T) {
+	resetAuth(t, args.RabbitMQArguments{Hostname: "localhost", Port: 15672, Username: "user", BearerToken: "static-token"})
+	req, err := createRequest(NodesEndpoint)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer static-token", req.Header.Get("Authorization"))
+	_, _, ok := req.BasicAuth()
+	assert.False(t, ok)
+}
+
+func Test_createRequest_BearerTokenFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	require.NoError(t, os.WriteFile(path, []byte("file-token\n"), 0o600))
+	resetAuth(t, args.RabbitMQArguments{Hostname: "localhost", Port: 15672, BearerTokenFile: path})
+
+	req, err := createRequest(NodesEndpoint)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer file-token", req.Header.Get("Authorization"))
+
+	// the file is read once per run
+	require.NoError(t, os.WriteFile(path, []byte("rotated-token"), 0o600))
+	req, err = createRequest(NodesEndpoint)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer file-token", req.Header.Get("Authorization"))
+
+	bearerToken = ""
+	req, err = createRequest(NodesEndpoint)
+	require.NoError(t, err)
+	assert.Equal(t, "Bearer rotated-token", req.Header.Get("Authorization"))
+
+	require.NoError(t, os.WriteFile(path, []byte(" \n"), 0o600))
+	bearerToken = ""
+	_, err = createRequest(NodesEndpoint)
+	assert.Error(t, err)
+
+	resetAuth(t, args.RabbitMQArguments{Hostname: "localhost", Port: 15672, BearerTokenFile: filepath.Join(t.TempDir(), "missing")})
+	_, err = createRequest(NodesEndpoint)
+	assert.Error(t, err)
+}
+
+func Test_createRequest_ClientCredentials(t *testing.T) {
+	resetAuth(t, args.RabbitMQArguments{})
+	mux, teardown := testutils.GetTestServer(false)
+	defer teardown()
+	tokenRequests := 0
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		tokenRequests++
+		username, password, _ := r.BasicAuth()
+		if r.Method != http.MethodPost || username != "rabbit%2Fclient" || password != "secret" ||
+			r.FormValue("grant_type") != "client_credentials" || r.FormValue("scope") != "rabbitmq.read:*/*" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Add("content-type", "application/json")
+		fmt.Fprint(w, `{"access_token": "oauth-token", "token_type": "bearer", "expires_in": 3600}`)
+	})
+	mux.HandleFunc(OverviewEndpoint, func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer oauth-token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		w.Header().Add("content-type", "application/json")
+		fmt.Fprint(w, `{"cluster_name": "cluster1"}`)
+	})
+	args.GlobalArgs.OauthTokenURL = fmt.Sprintf("http://%s:%d/token", args.GlobalArgs.Hostname, args.GlobalArgs.Port)
+	args.GlobalArgs.OauthClientID = "rabbit/client"
+	args.GlobalArgs.OauthClientSecret = "secret"
+	args.GlobalArgs.OauthScope = "rabbitmq.read:*/*"
+
+	var overview struct {
+		ClusterName string `json:"cluster_name"`
+	}
+	require.NoError(t, CollectEndpoint(OverviewEndpoint, &overview))
+	assert.Equal(t, "cluster1", overview.ClusterName)
+	require.NoError(t, CollectEndpoint(OverviewEndpoint, &overview))
+	assert.Equal(t, 1, tokenRequests, "the token is requested once per run")
+
+	bearerToken = ""
+	args.GlobalArgs.OauthClientSecret = "wrong"
+	assert.Error(t, CollectEndpoint(OverviewEndpoint, &overview))
+}
+
+func Test_requestClientCredentialsToken_Errors(t *testing.T) {
+	resetAuth(t, args.RabbitMQArguments{})
+	mux, teardown := testutils.GetTestServer(false)
+	defer teardown()
+	responses := map[string]string{
+		"/no-token":    `{"token_type": "bearer"}`,
+		"/mac-token":   `{"access_token": "token", "token_type": "mac"}`,
+		"/not-json":    `token`,
+		"/unavailable": "",
+	}
+	for path, response := range responses {
+		response := response
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			if response == "" {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				return
+			}
+			fmt.Fprint(w, response)
+		})
+	}
+	for path := range responses {
+		args.GlobalArgs.OauthTokenURL = fmt.Sprintf("http://%s:%d%s", args.GlobalArgs.Hostname, args.GlobalArgs.Port, path)
+		_, err := requestClientCredentialsToken()
+		assert.Error(t, err, path)
+	}
+}
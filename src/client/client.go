@@ -34,8 +34,20 @@ const (
 	AlivenessTestEndpoint = "/api/aliveness-test/%s"
 	// HealthCheckEndpoint path, this is formatted with the node name
 	HealthCheckEndpoint = "/api/healthchecks/node/%s"
+	// ShovelsEndpoint path, only available when the rabbitmq_shovel_management plugin is enabled
+	ShovelsEndpoint = "/api/shovels"
+	// FederationLinksEndpoint path, only available when the rabbitmq_federation_management plugin is enabled
+	FederationLinksEndpoint = "/api/federation-links"
+	// FeatureFlagsEndpoint path
+	FeatureFlagsEndpoint = "/api/feature-flags"
+	// DeprecatedFeaturesUsedEndpoint path, only available on RabbitMQ 3.13 and later
+	DeprecatedFeaturesUsedEndpoint = "/api/deprecated-features/used"
 )
 
+// ErrEndpointNotFound is returned when the Management API responds with a 404, usually because the plugin
+// serving the endpoint is not enabled or the RabbitMQ version does not support it
+var ErrEndpointNotFound = errors.New("endpoint not found")
+
 var defaultClient *http.Client
 
 // CollectEndpoint calls the endpoint and populates its response into result
@@ -71,17 +83,22 @@ func collectEndpoint(req *http.Request, jsonResult interface{}) error {
 	if err != nil {
 		return err
 	}
-	if resp.StatusCode != 200 || !strings.HasPrefix(resp.Header.Get("content-type"), "application/json") {
-		err := fmt.Errorf("unexpected http response from [%s]: %s", req.URL, resp.Status)
-		log.Error("Error making API call: %v", err)
-		return err
-	}
 	defer func() {
 		if err := resp.Body.Close(); err != nil {
 			log.Error("Error closing response body: %v", err)
 		}
 	}()
 
+	if resp.StatusCode == http.StatusNotFound {
+		log.Debug("Endpoint [%s] is not available: %s", req.URL, resp.Status)
+		return fmt.Errorf("%w: %s", ErrEndpointNotFound, req.URL)
+	}
+	if resp.StatusCode != 200 || !strings.HasPrefix(resp.Header.Get("content-type"), "application/json") {
+		err := fmt.Errorf("unexpected http response from [%s]: %s", req.URL, resp.Status)
+		log.Error("Error making API call: %v", err)
+		return err
+	}
+
 	if err = json.NewDecoder(resp.Body).Decode(jsonResult); err != nil {
 		return err
 	}
@@ -122,6 +139,8 @@ func createRequest(endpoint string) (*http.Request, error) {
 	if err != nil {
 		return nil, err
 	}
-	req.SetBasicAuth(args.GlobalArgs.Username, args.GlobalArgs.Password)
+	if err := setAuthorization(req); err != nil {
+		return nil, err
+	}
 	return req, nil
 }
@@ -110,7 +110,7 @@ func TestCollectEndpoint_Errors(t *testing.T) {
 	})
 
 	err := CollectEndpoint(ConnectionsEndpoint, &struct{}{})
-	assert.Error(t, err)
+	assert.ErrorIs(t, err, ErrEndpointNotFound)
 
 	defaultClient = nil
 	args.GlobalArgs.Hostname = "[" + args.GlobalArgs.Hostname
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"github.com/newrelic/nri-rabbitmq/src/data"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/log"
+)
+
+// isClusterCollector returns true if the local node is the designated collector of the cluster-wide entities, which
+// is the first running node by name. Every instance picks the same node from the nodes endpoint, so no coordination
+// is needed between them.
+func isClusterCollector(nodes []*data.NodeData, localNodeName string) bool {
+	if localNodeName == "" {
+		log.Warn("The local node name could not be determined, the cluster-wide entities are not reported. Use NodeNameOverride to set it.")
+		return false
+	}
+	collector := getClusterCollector(nodes)
+	if collector != localNodeName {
+		log.Debug("The cluster-wide entities are reported by the instance of node [%s]", collector)
+		return false
+	}
+	return true
+}
+
+// getClusterCollector returns the name of the first running node by name, or an empty string if no node is running
+func getClusterCollector(nodes []*data.NodeData) string {
+	var collector string
+	for _, node := range nodes {
+		if node.Running == nil || !*node.Running {
+			continue
+		}
+		if collector == "" || node.Name < collector {
+			collector = node.Name
+		}
+	}
+	return collector
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/newrelic/nri-rabbitmq/src/data"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_isClusterCollector(t *testing.T) {
+	running, stopped := true, false
+	nodes := []*data.NodeData{
+		{Name: "rabbit@host-3", Running: &running},
+		{Name: "rabbit@host-1", Running: &stopped},
+		{Name: "rabbit@host-2", Running: &running},
+		{Name: "rabbit@host-0"},
+	}
+	assert.Equal(t, "rabbit@host-2", getClusterCollector(nodes))
+	assert.True(t, isClusterCollector(nodes, "rabbit@host-2"))
+	assert.False(t, isClusterCollector(nodes, "rabbit@host-3"))
+	assert.False(t, isClusterCollector(nodes, "rabbit@host-1"), "stopped nodes are not collectors")
+	assert.False(t, isClusterCollector(nodes, ""))
+
+	assert.Equal(t, "", getClusterCollector(nodes[1:2]))
+	assert.False(t, isClusterCollector(nil, "rabbit@host-2"))
+}
@@ -0,0 +1,57 @@
+package data
+
+import (
+	"github.com/newrelic/nri-rabbitmq/src/data/consts"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/data/attribute"
+	"github.com/newrelic/infra-integrations-sdk/v3/integration"
+)
+
+// ClusterData holds the cluster wide metrics computed from the nodes endpoint
+type ClusterData struct {
+	Name              string
+	NodesTotal        int     `metric_name:"cluster.nodesTotal" source_type:"gauge"`
+	NodesRunning      int     `metric_name:"cluster.nodesRunning" source_type:"gauge"`
+	Partitioned       int     `metric_name:"cluster.partitioned" source_type:"gauge"`
+	NodesPartitioned  int     `metric_name:"cluster.nodesPartitioned" source_type:"gauge"`
+	PartitionHandling *string `metric_name:"cluster.partitionHandling" source_type:"attribute"`
+}
+
+// NewClusterData computes the cluster metrics from the nodes. partitionHandling is the configured
+// cluster_partition_handling strategy, left out of the metrics when empty.
+func NewClusterData(clusterName string, nodes []*NodeData, partitionHandling string) *ClusterData {
+	cluster := &ClusterData{Name: clusterName, NodesTotal: len(nodes)}
+	for _, node := range nodes {
+		if node.Running != nil && *node.Running {
+			cluster.NodesRunning++
+		}
+		if node.Partitions > 0 {
+			cluster.NodesPartitioned++
+		}
+	}
+	cluster.Partitioned = ConvertBoolToInt(cluster.NodesPartitioned > 0)
+	if partitionHandling != "" {
+		cluster.PartitionHandling = &partitionHandling
+	}
+	return cluster
+}
+
+// GetEntity creates an integration.Entity for this ClusterData
+func (c *ClusterData) GetEntity(integration *integration.Integration, clusterName string) (*integration.Entity, []attribute.Attribute, error) {
+	return CreateEntity(integration, c.Name, consts.ClusterType, "", clusterName)
+}
+
+// EntityType returns the type of this entity
+func (c *ClusterData) EntityType() string {
+	return consts.ClusterType
+}
+
+// EntityName returns the main name of this entity
+func (c *ClusterData) EntityName() string {
+	return c.Name
+}
+
+// EntityVhost returns the vhost of this entity
+func (c *ClusterData) EntityVhost() string {
+	return ""
+}
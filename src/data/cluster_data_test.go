@@ -0,0 +1,47 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/newrelic/nri-rabbitmq/src/args"
+	"github.com/newrelic/nri-rabbitmq/src/data/consts"
+	"github.com/newrelic/nri-rabbitmq/src/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClusterData(t *testing.T) {
+	existingArgs := args.GlobalArgs
+	defer func() {
+		args.GlobalArgs = existingArgs
+	}()
+	args.GlobalArgs = args.RabbitMQArguments{Vhosts: []string{"vhost1"}}
+	running := true
+	nodes := []*NodeData{
+		{Name: "rabbit@host1", Partitions: 1, PartitionPeers: []string{"rabbit@host2"}, Running: &running},
+		{Name: "rabbit@host2", Partitions: 1, PartitionPeers: []string{"rabbit@host1"}},
+		{Name: "rabbit@host3"},
+	}
+	clusterData := NewClusterData("cluster1", nodes, "pause_minority")
+	assert.Equal(t, "cluster1", clusterData.EntityName())
+	assert.Equal(t, consts.ClusterType, clusterData.EntityType())
+	assert.Equal(t, "", clusterData.EntityVhost())
+
+	e, metricAttribs, err := clusterData.GetEntity(testutils.GetTestingIntegration(t), "cluster1")
+	require.NoError(t, err)
+	require.NotNil(t, e, "the cluster entity must not be filtered by vhost")
+
+	ms := e.NewMetricSet("RabbitmqClusterSample", metricAttribs...)
+	assert.NoError(t, ms.MarshalMetrics(clusterData))
+	assert.Equal(t, float64(3), ms.Metrics["cluster.nodesTotal"])
+	assert.Equal(t, float64(1), ms.Metrics["cluster.nodesRunning"])
+	assert.Equal(t, float64(1), ms.Metrics["cluster.partitioned"])
+	assert.Equal(t, float64(2), ms.Metrics["cluster.nodesPartitioned"])
+	assert.Equal(t, "pause_minority", ms.Metrics["cluster.partitionHandling"])
+
+	clusterData = NewClusterData("cluster1", nodes[2:], "")
+	assert.Equal(t, 0, clusterData.Partitioned)
+	assert.Equal(t, 0, clusterData.NodesPartitioned)
+	assert.Nil(t, clusterData.PartitionHandling)
+}
@@ -4,12 +4,20 @@ const (
 	// DefaultExchangeName is the common name to give the exchange with an empty name
 	DefaultExchangeName = "amq.default"
 
+	// ClusterType name
+	ClusterType = "cluster"
 	// NodeType name
 	NodeType = "node"
 	// VhostType name
 	VhostType = "vhost"
 	// QueueType name
 	QueueType = "queue"
+	// QueueGroupType name, queue groups are reported on their vhost entity
+	QueueGroupType = "queueGroup"
 	// ExchangeType name
 	ExchangeType = "exchange"
+	// ShovelType name
+	ShovelType = "shovel"
+	// FederationLinkType name
+	FederationLinkType = "federationLink"
 )
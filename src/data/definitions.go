@@ -13,15 +13,40 @@ type EntityData interface {
 	EntityType() string
 }
 
+// LinkRunning is the state reported by shovels and federation links that are running
+const LinkRunning = "running"
+
+// LinkData is implemented by shovels and federation links, which move messages between brokers
+type LinkData interface {
+	EntityData
+	IsRunning() bool
+	LinkState() string
+	LinkError() string
+}
+
+// SampleData is implemented by the data reported as one of several samples of the same type on its entity, such as the
+// queue groups of a vhost, which are told apart by their SampleAttributes
+type SampleData interface {
+	EntityData
+	SampleAttributes() map[string]interface{}
+}
+
 // OverviewData is the representation of the overview endpoint
 type OverviewData struct {
 	ClusterName       string `json:"cluster_name"`
+	Node              string `json:"node"`
 	RabbitMQVersion   string `json:"rabbitmq_version"`
 	ManagementVersion string `json:"management_version"`
 }
 
+// ConnectionBlocked is the state of the connections blocked from publishing by a resource alarm
+const ConnectionBlocked = "blocked"
+
 // ConnectionData is the representation of the connections endpoint
 type ConnectionData struct {
+	Name  string
+	User  string
+	Node  string
 	Vhost string
 	State string
 }
@@ -24,6 +24,7 @@ type ExchangeData struct {
 	Type       string
 	Durable    bool
 	AutoDelete bool `json:"auto_delete"`
+	Internal   bool
 	Arguments  map[string]interface{}
 }
 
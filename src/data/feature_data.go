@@ -0,0 +1,41 @@
+package data
+
+const (
+	// FeatureFlagEnabled is the state of an enabled feature flag
+	FeatureFlagEnabled = "enabled"
+	// FeatureFlagDisabled is the state of a disabled feature flag
+	FeatureFlagDisabled = "disabled"
+	// FeatureFlagStable is the stability of a feature flag that the next minor or major version may make required,
+	// so it must be enabled before upgrading
+	FeatureFlagStable = "stable"
+)
+
+// FeatureFlagData is the representation of the feature-flags endpoint
+type FeatureFlagData struct {
+	Name       string
+	Desc       string
+	State      string
+	Stability  string
+	ProvidedBy string `json:"provided_by"`
+}
+
+// IsStableAndDisabled returns true if the feature flag is stable but disabled, which blocks upgrading to a version
+// where it's required. Required flags are always enabled on a running node.
+func (f *FeatureFlagData) IsStableAndDisabled() bool {
+	return f.Stability == FeatureFlagStable && f.State == FeatureFlagDisabled
+}
+
+// DeprecatedFeatureData is the representation of the deprecated-features/used endpoint
+type DeprecatedFeatureData struct {
+	Name             string
+	Desc             string
+	DeprecationPhase string `json:"deprecation_phase"`
+	ProvidedBy       string `json:"provided_by"`
+	DocURL           string `json:"doc_url"`
+}
+
+// FeaturesData holds the feature flags of the cluster and the deprecated features in use
+type FeaturesData struct {
+	FeatureFlags       []*FeatureFlagData
+	DeprecatedFeatures []*DeprecatedFeatureData
+}
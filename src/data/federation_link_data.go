@@ -0,0 +1,85 @@
+package data
+
+import (
+	"encoding/json"
+
+	"github.com/newrelic/nri-rabbitmq/src/data/consts"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/data/attribute"
+	"github.com/newrelic/infra-integrations-sdk/v3/integration"
+)
+
+// FederationLinkData is the representation of the federation-links endpoint
+type FederationLinkData struct {
+	Vhost            string
+	Upstream         string  `metric_name:"federationLink.upstream" source_type:"attribute"`
+	Node             string  `metric_name:"federationLink.node" source_type:"attribute"`
+	Type             string  `metric_name:"federationLink.type" source_type:"attribute"`
+	Exchange         string  `json:"exchange"`
+	UpstreamExchange string  `json:"upstream_exchange"`
+	Queue            string  `json:"queue"`
+	UpstreamQueue    string  `json:"upstream_queue"`
+	Status           string  `metric_name:"federationLink.state" source_type:"attribute"`
+	Running          int     `json:"-" metric_name:"federationLink.running" source_type:"gauge"`
+	URI              *string `json:"-" metric_name:"federationLink.uri" source_type:"attribute"`
+	Error            *string `metric_name:"federationLink.error" source_type:"attribute"`
+}
+
+// GetEntity creates an integration.Entity for this FederationLinkData
+func (f *FederationLinkData) GetEntity(integration *integration.Integration, clusterName string) (*integration.Entity, []attribute.Attribute, error) {
+	return CreateEntity(integration, f.EntityName(), consts.FederationLinkType, f.Vhost, clusterName)
+}
+
+// EntityType returns the type of this entity
+func (f *FederationLinkData) EntityType() string {
+	return consts.FederationLinkType
+}
+
+// EntityName returns the main name of this entity, made of the upstream and the local exchange or queue it federates
+func (f *FederationLinkData) EntityName() string {
+	if f.Type == consts.QueueType {
+		return f.Upstream + "/" + f.Queue
+	}
+	return f.Upstream + "/" + cleanEntityName(f.Exchange, consts.ExchangeType)
+}
+
+// EntityVhost returns the vhost of this entity
+func (f *FederationLinkData) EntityVhost() string {
+	return f.Vhost
+}
+
+// IsRunning returns true if the federation link is in the running state
+func (f *FederationLinkData) IsRunning() bool {
+	return f.Status == LinkRunning
+}
+
+// LinkState returns the state reported for the federation link
+func (f *FederationLinkData) LinkState() string {
+	return f.Status
+}
+
+// LinkError returns the error reported for the federation link, if any
+func (f *FederationLinkData) LinkError() string {
+	if f.Error == nil {
+		return ""
+	}
+	return redactURICredentials(*f.Error)
+}
+
+// UnmarshalJSON handles custom JSON Unmarshaling in order to redact the credentials from the upstream URI and the link error
+func (f *FederationLinkData) UnmarshalJSON(data []byte) error {
+	type Alias FederationLinkData
+	aux := &struct {
+		URI interface{} `json:"uri"`
+		*Alias
+	}{
+		Alias: (*Alias)(f),
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	f.URI = redactURIs(aux.URI)
+	f.Error = redactErrorText(f.Error)
+	f.Running = ConvertBoolToInt(f.IsRunning())
+	return nil
+}
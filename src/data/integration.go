@@ -21,8 +21,11 @@ func CreateEntity(rabbitmqIntegration *integration.Integration, entityName, enti
 		log.Debug("Skipping entity with name: %s, entity type: %s, vhost: %s", entityName, entityType, vhost)
 		return nil, nil, nil
 	}
+	return newEntity(rabbitmqIntegration, name, entityType, vhost, clusterName)
+}
 
-	if entityType == consts.QueueType || entityType == consts.ExchangeType {
+func newEntity(rabbitmqIntegration *integration.Integration, name, entityType, vhost, clusterName string) (*integration.Entity, []attribute.Attribute, error) {
+	if isVhostScoped(entityType) {
 		name = joinVhostName(vhost, name)
 	}
 	metricNamespace := []attribute.Attribute{
@@ -42,6 +45,15 @@ func CreateEntity(rabbitmqIntegration *integration.Integration, entityName, enti
 	return entity, metricNamespace, nil
 }
 
+// isVhostScoped returns true for entity types whose names are only unique within a vhost
+func isVhostScoped(entityType string) bool {
+	switch entityType {
+	case consts.QueueType, consts.ExchangeType, consts.ShovelType, consts.FederationLinkType:
+		return true
+	}
+	return false
+}
+
 func cleanEntityName(entityName, entityType string) string {
 	if entityType == consts.ExchangeType && entityName == "" {
 		return consts.DefaultExchangeName
@@ -61,6 +73,7 @@ func SetInventoryItem(entity *integration.Entity, category, key string, value in
 	if entity == nil || key == "" || value == nil {
 		return
 	}
+	value = RedactInventoryValue(key, value)
 	if category != "" {
 		key = category + "/" + key
 	}
@@ -105,7 +118,7 @@ func setInventoryMap(entity *integration.Entity, category, key string, value map
 			if arrayVal, ok := v.([]interface{}); ok {
 				setInventoryArray(entity, key, k, arrayVal)
 			} else {
-				if err := entity.SetInventoryItem(key, k, v); err != nil {
+				if err := entity.SetInventoryItem(key, k, RedactInventoryValue(k, v)); err != nil {
 					logInventoryErr(entity, err, key)
 				}
 			}
@@ -117,7 +130,7 @@ func setInventoryArray(entity *integration.Entity, key, field string, v []interf
 	if arrayJSON, err := json.Marshal(v); err != nil {
 		logInventoryErr(entity, err, key)
 	} else {
-		if err := entity.SetInventoryItem(key, field, string(arrayJSON)); err != nil {
+		if err := entity.SetInventoryItem(key, field, RedactInventoryValue(field, string(arrayJSON))); err != nil {
 			logInventoryErr(entity, err, key)
 		}
 	}
@@ -2,8 +2,10 @@ package data
 
 import (
 	"encoding/json"
+	"fmt"
 	"math/big"
 
+	"github.com/newrelic/nri-rabbitmq/src/args"
 	"github.com/newrelic/nri-rabbitmq/src/data/consts"
 
 	"github.com/newrelic/infra-integrations-sdk/v3/data/attribute"
@@ -17,13 +19,16 @@ type NodeData struct {
 	ConfigFiles          []string `json:"config_files"`
 	DiskAlarm            *bool    `json:"disk_free_alarm" metric_name:"node.diskAlarm" source_type:"gauge"`
 	DiskFreeSpace        *int64   `json:"disk_free" metric_name:"node.diskSpaceFreeInBytes" source_type:"gauge"`
+	DiskFreeLimit        *int64   `json:"disk_free_limit" metric_name:"node.diskSpaceFreeLimitInBytes" source_type:"gauge"`
 	FileDescriptorsUsed  *int64   `json:"fd_used" metric_name:"node.fileDescriptorsTotalUsed" source_type:"gauge"`
 	FileDescriptorsTotal *int64   `json:"fd_total" metric_name:"node.fileDescriptorsTotal" source_type:"gauge"`
 	ProcessesTotal       *int64   `json:"proc_total" metric_name:"node.processesTotal" source_type:"gauge"`
 	ProcessesUsed        *int64   `json:"proc_used" metric_name:"node.processesUsed" source_type:"gauge"`
 	MemoryAlarm          *bool    `json:"mem_alarm" metric_name:"node.hostMemoryAlarm" source_type:"gauge"`
 	MemoryUsed           *int64   `json:"mem_used" metric_name:"node.totalMemoryUsedInBytes" source_type:"gauge"`
+	MemoryLimit          *int64   `json:"mem_limit" metric_name:"node.memoryLimitInBytes" source_type:"gauge"`
 	Partitions           int      `json:"-" metric_name:"node.partitionsSeen" source_type:"gauge"`
+	PartitionPeers       []string `json:"-"`
 	Running              *bool    `metric_name:"node.running" source_type:"gauge"`
 	RunQueue             *int64   `json:"run_queue" metric_name:"node.averageErlangProcessesWaiting" source_type:"gauge"`
 	SocketsTotal         *int64   `json:"sockets_total" metric_name:"node.fileDescriptorsTotalSockets" source_type:"gauge"`
@@ -35,6 +40,16 @@ func (n *NodeData) GetEntity(integration *integration.Integration, clusterName s
 	return CreateEntity(integration, n.Name, consts.NodeType, "", clusterName)
 }
 
+// GetStatusEntity returns the entity of the node to report its running status on, which is also created for the nodes
+// skipped by LocalNodeOnly when this instance is the cluster collector
+func (n *NodeData) GetStatusEntity(integration *integration.Integration, clusterName string) (*integration.Entity, []attribute.Attribute, error) {
+	if !args.GlobalArgs.IncludeNodeStatus(n.Name) {
+		log.Debug("Skipping the running status of node: %s", n.Name)
+		return nil, nil, nil
+	}
+	return newEntity(integration, n.Name, consts.NodeType, "", clusterName)
+}
+
 // EntityType returns the type of this entity
 func (n *NodeData) EntityType() string {
 	return consts.NodeType
@@ -65,6 +80,10 @@ func (n *NodeData) UnmarshalJSON(data []byte) error {
 		return err
 	}
 	n.Partitions = len(aux.Partitions)
+	n.PartitionPeers = make([]string, len(aux.Partitions))
+	for i, peer := range aux.Partitions {
+		n.PartitionPeers[i] = fmt.Sprint(peer)
+	}
 	if aux.DiskFreeSpace == nil {
 		return nil
 	}
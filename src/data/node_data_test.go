@@ -27,8 +27,11 @@ func TestNodeData_UnmarshalJSON_MarshalMetrics(t *testing.T) {
 	assert.Equal(t, getInt64(1048576), nodeData.ProcessesTotal)
 	assert.Equal(t, getInt64(5180), nodeData.ProcessesUsed)
 	assert.Equal(t, getInt64(2048), nodeData.MemoryUsed)
+	assert.Equal(t, getInt64(4096), nodeData.MemoryLimit)
+	assert.Equal(t, getInt64(512), nodeData.DiskFreeLimit)
 	assert.Equal(t, "node1", nodeData.Name)
 	assert.Equal(t, 2, nodeData.Partitions)
+	assert.Equal(t, []string{"one", "two"}, nodeData.PartitionPeers)
 	assert.Equal(t, getInt64(3), nodeData.RunQueue)
 	assert.Equal(t, getBool(true), nodeData.Running)
 	assert.Equal(t, getInt64(2), nodeData.SocketsUsed)
@@ -52,6 +55,8 @@ func TestNodeData_UnmarshalJSON_MarshalMetrics(t *testing.T) {
 	assert.Equal(t, float64(5180), ms.Metrics["node.processesUsed"])
 	assert.Equal(t, float64(1024), ms.Metrics["node.diskSpaceFreeInBytes"])
 	assert.Equal(t, float64(2048), ms.Metrics["node.totalMemoryUsedInBytes"])
+	assert.Equal(t, float64(4096), ms.Metrics["node.memoryLimitInBytes"])
+	assert.Equal(t, float64(512), ms.Metrics["node.diskSpaceFreeLimitInBytes"])
 	assert.Equal(t, float64(3), ms.Metrics["node.averageErlangProcessesWaiting"])
 	assert.Equal(t, float64(2), ms.Metrics["node.fileDescriptorsUsedSockets"])
 	assert.Equal(t, float64(58890), ms.Metrics["node.fileDescriptorsTotalSockets"])
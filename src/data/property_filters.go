@@ -0,0 +1,75 @@
+package data
+
+import (
+	"fmt"
+
+	"github.com/newrelic/nri-rabbitmq/src/args"
+)
+
+// MatchesFilter returns true if the queue properties pass the filter, or if there is no filter
+func (q *QueueData) MatchesFilter(filter *args.QueueFilter) bool {
+	if filter == nil {
+		return true
+	}
+	queueType := q.Type
+	if queueType == "" {
+		queueType = args.QueueTypeClassic
+	}
+	return matchesFlag(filter.Exclusive, q.Exclusive) &&
+		matchesFlag(filter.AutoDelete, q.AutoDelete) &&
+		matchesFlag(filter.Durable, q.Durable) &&
+		matchesType(queueType, filter.Types, filter.ExcludeTypes) &&
+		matchesArguments(q.Arguments, filter.Arguments, filter.ExcludeArguments)
+}
+
+// MatchesFilter returns true if the exchange properties pass the filter, or if there is no filter
+func (e *ExchangeData) MatchesFilter(filter *args.ExchangeFilter) bool {
+	if filter == nil {
+		return true
+	}
+	return matchesFlag(filter.AutoDelete, e.AutoDelete) &&
+		matchesFlag(filter.Durable, e.Durable) &&
+		matchesFlag(filter.Internal, e.Internal) &&
+		matchesType(e.Type, filter.Types, filter.ExcludeTypes) &&
+		matchesArguments(e.Arguments, filter.Arguments, filter.ExcludeArguments)
+}
+
+func matchesFlag(expected *bool, value bool) bool {
+	return expected == nil || *expected == value
+}
+
+func matchesType(value string, types, excludeTypes []string) bool {
+	for _, excludeType := range excludeTypes {
+		if value == excludeType {
+			return false
+		}
+	}
+	if len(types) == 0 {
+		return true
+	}
+	for _, includeType := range types {
+		if value == includeType {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesArguments(arguments, required map[string]interface{}, excluded []string) bool {
+	for _, name := range excluded {
+		if _, ok := arguments[name]; ok {
+			return false
+		}
+	}
+	for name, expected := range required {
+		value, ok := arguments[name]
+		if !ok {
+			return false
+		}
+		// the values are compared as strings, so 1 and 1.0 decoded from JSON are equal
+		if expected != nil && fmt.Sprint(value) != fmt.Sprint(expected) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,56 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/newrelic/nri-rabbitmq/src/args"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueueData_MatchesFilter(t *testing.T) {
+	no := false
+	replyQueue := &QueueData{Name: "amq.gen-1", Exclusive: true, AutoDelete: true}
+	quorumQueue := &QueueData{Name: "orders", Durable: true, Type: args.QueueTypeQuorum, Arguments: map[string]interface{}{"x-queue-type": "quorum", "x-max-length": float64(1000)}}
+	ttlQueue := &QueueData{Name: "cache", Arguments: map[string]interface{}{"x-expires": float64(60000)}}
+
+	assert.True(t, replyQueue.MatchesFilter(nil))
+
+	filter := &args.QueueFilter{Exclusive: &no, AutoDelete: &no}
+	assert.False(t, replyQueue.MatchesFilter(filter))
+	assert.True(t, quorumQueue.MatchesFilter(filter))
+
+	filter = &args.QueueFilter{Types: []string{args.QueueTypeQuorum}}
+	assert.True(t, quorumQueue.MatchesFilter(filter))
+	assert.False(t, ttlQueue.MatchesFilter(filter))
+
+	filter = &args.QueueFilter{ExcludeTypes: []string{args.QueueTypeClassic}}
+	assert.False(t, ttlQueue.MatchesFilter(filter), "queues without a type are classic queues")
+
+	filter = &args.QueueFilter{Arguments: map[string]interface{}{"x-max-length": float64(1000), "x-queue-type": nil}}
+	assert.True(t, quorumQueue.MatchesFilter(filter))
+	assert.False(t, ttlQueue.MatchesFilter(filter))
+
+	filter = &args.QueueFilter{Arguments: map[string]interface{}{"x-max-length": float64(10)}}
+	assert.False(t, quorumQueue.MatchesFilter(filter))
+
+	filter = &args.QueueFilter{ExcludeArguments: []string{"x-expires"}}
+	assert.False(t, ttlQueue.MatchesFilter(filter))
+	assert.True(t, quorumQueue.MatchesFilter(filter))
+}
+
+func TestExchangeData_MatchesFilter(t *testing.T) {
+	no := false
+	delayed := &ExchangeData{Name: "delayed", Type: "x-delayed-message"}
+	internal := &ExchangeData{Name: "internal", Type: "topic", Internal: true}
+
+	assert.True(t, delayed.MatchesFilter(nil))
+
+	filter := &args.ExchangeFilter{ExcludeTypes: []string{"x-delayed-message"}}
+	assert.False(t, delayed.MatchesFilter(filter))
+	assert.True(t, internal.MatchesFilter(filter))
+
+	filter = &args.ExchangeFilter{Internal: &no, Types: []string{"topic", "x-delayed-message"}}
+	assert.True(t, delayed.MatchesFilter(filter))
+	assert.False(t, internal.MatchesFilter(filter))
+}
@@ -14,10 +14,12 @@ import (
 type QueueData struct {
 	Name                string
 	Vhost               string
+	Node                string
 	Exclusive           bool
 	Durable             bool
 	Arguments           map[string]interface{}
-	AutoDelete          bool     `json:"auto_delete"`
+	AutoDelete          bool `json:"auto_delete"`
+	Type                string
 	Consumers           *int64   `metric_name:"queue.consumers" source_type:"gauge"`
 	ConsumerUtilisation *float64 `json:"consumer_utilisation" metric_name:"queue.consumerMessageUtilizationPerSecond" source_type:"gauge"`
 	ActiveConsumers     *int64   `json:"active_consumers" metric_name:"queue.countActiveConsumersReceiveMessages" source_type:"gauge"`
@@ -56,6 +58,12 @@ type QueueData struct {
 			Rate *float64 `metric_name:"queue.messagesRedeliverGetPerSecond" source_type:"gauge"`
 		} `json:"redeliver_details"`
 	} `json:"message_stats"`
+	BacklogGrowth *float64 `json:"-" metric_name:"queue.backlogGrowthPerSecond" source_type:"gauge"`
+	NetInflow     *float64 `json:"-" metric_name:"queue.netInflowPerSecond" source_type:"gauge"`
+	TimeToDrain   *float64 `json:"-" metric_name:"queue.timeToDrainInSeconds" source_type:"gauge"`
+	// Group is set from the QueueGroupTemplates, GroupCaptures are reported as queue.group.<capture> attributes
+	Group         *string           `json:"-" metric_name:"queue.group" source_type:"attribute"`
+	GroupCaptures map[string]string `json:"-"`
 }
 
 // CollectInventory collects inventory data and reports it to the integration.Entity
@@ -34,6 +34,7 @@ func TestQueueData_UnmarshalJSON_MarshalMetrics(t *testing.T) {
 	assert.False(t, queueData.AutoDelete)
 	assert.True(t, queueData.Durable)
 	assert.Equal(t, "vhost1", queueData.Vhost)
+	assert.Equal(t, "rabbit@host1", queueData.Node)
 	assert.Equal(t, "queue1", queueData.Name)
 	assert.Equal(t, getInt64(9), queueData.Consumers)
 	assert.Equal(t, getInt64(10), queueData.ActiveConsumers)
@@ -0,0 +1,136 @@
+package data
+
+import (
+	"math"
+	"sort"
+
+	"github.com/newrelic/nri-rabbitmq/src/data/consts"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/data/attribute"
+	"github.com/newrelic/infra-integrations-sdk/v3/integration"
+)
+
+const (
+	// OtherQueuesGroup is the group summarising the queues left out of the collection by the queue limit
+	OtherQueuesGroup = "other"
+	// AllQueuesGroup is the group summarising all the queues of a vhost
+	AllQueuesGroup = "all"
+)
+
+// The QueueGroupData types, telling apart groups with the same name
+const (
+	QueueGroupTypeAll      = "all"
+	QueueGroupTypeRegex    = "regex"
+	QueueGroupTypeTemplate = "template"
+	QueueGroupTypeOther    = "other"
+)
+
+// QueueGroupData summarises a group of queues of a vhost, it's reported on the vhost entity.
+// Summarize must be called once all the queues are added to compute the percentiles.
+type QueueGroupData struct {
+	Vhost string
+	Name  string `metric_name:"queueGroup.name" source_type:"attribute"`
+	// Type is how the group was built, one of the QueueGroupType values
+	Type string `metric_name:"queueGroup.type" source_type:"attribute"`
+	// Node is set when only the queues of the local node are reported, as each instance reports its own summaries
+	Node                      string  `metric_name:"queueGroup.node" source_type:"attribute"`
+	Queues                    int     `metric_name:"queueGroup.queues" source_type:"gauge"`
+	Consumers                 int64   `metric_name:"queueGroup.consumers" source_type:"gauge"`
+	ConsumersMax              int64   `metric_name:"queueGroup.consumersMax" source_type:"gauge"`
+	ConsumersP95              int64   `metric_name:"queueGroup.consumersP95" source_type:"gauge"`
+	Memory                    int64   `metric_name:"queueGroup.erlangBytesConsumedInBytes" source_type:"gauge"`
+	Messages                  int64   `metric_name:"queueGroup.totalMessages" source_type:"gauge"`
+	MessagesMax               int64   `metric_name:"queueGroup.totalMessagesMax" source_type:"gauge"`
+	MessagesP95               int64   `metric_name:"queueGroup.totalMessagesP95" source_type:"gauge"`
+	MessagesReady             int64   `metric_name:"queueGroup.messagesReadyDeliveryClients" source_type:"gauge"`
+	MessagesUnacknowledged    int64   `metric_name:"queueGroup.messagesReadyUnacknowledged" source_type:"gauge"`
+	MessagesUnacknowledgedMax int64   `metric_name:"queueGroup.messagesReadyUnacknowledgedMax" source_type:"gauge"`
+	MessagesUnacknowledgedP95 int64   `metric_name:"queueGroup.messagesReadyUnacknowledgedP95" source_type:"gauge"`
+	PublishRate               float64 `metric_name:"queueGroup.messagesPublishedPerSecond" source_type:"gauge"`
+	AckRate                   float64 `metric_name:"queueGroup.messagesAcknowledgedPerSecond" source_type:"gauge"`
+	DeliverGetRate            float64 `metric_name:"queueGroup.sumMessagesDeliveredPerSecond" source_type:"gauge"`
+	RedeliverRate             float64 `metric_name:"queueGroup.messagesRedeliverGetPerSecond" source_type:"gauge"`
+
+	consumers, messages, unacknowledged []int64
+}
+
+// Add adds the queue to the group totals
+func (g *QueueGroupData) Add(q *QueueData) {
+	g.Queues++
+	g.Consumers += int64OrZero(q.Consumers)
+	g.Memory += int64OrZero(q.Memory)
+	g.Messages += int64OrZero(q.Messages)
+	g.MessagesReady += int64OrZero(q.MessagesReady)
+	g.MessagesUnacknowledged += int64OrZero(q.MessagesUnacknowledged)
+	g.PublishRate += float64OrZero(q.MessageStats.PublishDetails.Rate)
+	g.AckRate += float64OrZero(q.MessageStats.AckDetails.Rate)
+	g.DeliverGetRate += float64OrZero(q.MessageStats.DeliverGetDetails.Rate)
+	g.RedeliverRate += float64OrZero(q.MessageStats.RedeliverDetails.Rate)
+
+	g.consumers = append(g.consumers, int64OrZero(q.Consumers))
+	g.messages = append(g.messages, int64OrZero(q.Messages))
+	g.unacknowledged = append(g.unacknowledged, int64OrZero(q.MessagesUnacknowledged))
+}
+
+// Summarize computes the maximum and the 95th percentile of the queues added to the group
+func (g *QueueGroupData) Summarize() {
+	g.ConsumersMax, g.ConsumersP95 = maxAndP95(g.consumers)
+	g.MessagesMax, g.MessagesP95 = maxAndP95(g.messages)
+	g.MessagesUnacknowledgedMax, g.MessagesUnacknowledgedP95 = maxAndP95(g.unacknowledged)
+}
+
+// maxAndP95 returns the maximum and the nearest-rank 95th percentile of the values
+func maxAndP95(values []int64) (int64, int64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	sorted := make([]int64, len(values))
+	copy(sorted, values)
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i] < sorted[j]
+	})
+	rank := int(math.Ceil(0.95*float64(len(sorted)))) - 1
+	return sorted[len(sorted)-1], sorted[rank]
+}
+
+// GetEntity returns the entity of the vhost of this QueueGroupData
+func (g *QueueGroupData) GetEntity(integration *integration.Integration, clusterName string) (*integration.Entity, []attribute.Attribute, error) {
+	return CreateEntity(integration, g.Vhost, consts.VhostType, g.Vhost, clusterName)
+}
+
+// SampleAttributes returns the attributes identifying the sample of this group among the ones reported by its vhost
+func (g *QueueGroupData) SampleAttributes() map[string]interface{} {
+	return map[string]interface{}{
+		"queueGroup.name": g.Name,
+		"queueGroup.type": g.Type,
+	}
+}
+
+// EntityType returns the type of this entity
+func (g *QueueGroupData) EntityType() string {
+	return consts.QueueGroupType
+}
+
+// EntityName returns the main name of this entity
+func (g *QueueGroupData) EntityName() string {
+	return g.Name
+}
+
+// EntityVhost returns the vhost of this entity
+func (g *QueueGroupData) EntityVhost() string {
+	return g.Vhost
+}
+
+func int64OrZero(value *int64) int64 {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
+
+func float64OrZero(value *float64) float64 {
+	if value == nil {
+		return 0
+	}
+	return *value
+}
@@ -0,0 +1,80 @@
+package data
+
+import (
+	"testing"
+
+	"github.com/newrelic/nri-rabbitmq/src/args"
+	"github.com/newrelic/nri-rabbitmq/src/data/consts"
+	"github.com/newrelic/nri-rabbitmq/src/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueueGroupData(t *testing.T) {
+	existingArgs := args.GlobalArgs
+	defer func() {
+		args.GlobalArgs = existingArgs
+	}()
+	args.GlobalArgs = args.RabbitMQArguments{}
+
+	messages, consumers, rate := int64(10), int64(2), 1.5
+	queue := &QueueData{Name: "queue1", Vhost: "vhost1", Messages: &messages, Consumers: &consumers}
+	queue.MessageStats.PublishDetails.Rate = &rate
+
+	group := &QueueGroupData{Vhost: "vhost1", Name: OtherQueuesGroup, Type: QueueGroupTypeOther}
+	group.Add(queue)
+	group.Add(queue)
+	group.Add(&QueueData{Name: "queue2", Vhost: "vhost1"})
+	assert.Equal(t, consts.QueueGroupType, group.EntityType())
+	assert.Equal(t, OtherQueuesGroup, group.EntityName())
+	assert.Equal(t, "vhost1", group.EntityVhost())
+
+	e, metricAttribs, err := group.GetEntity(testutils.GetTestingIntegration(t), "testClusterName")
+	require.NoError(t, err)
+	require.NotNil(t, e)
+	assert.Equal(t, "ra-vhost", e.Metadata.Namespace, "queue groups are reported on their vhost entity")
+
+	ms := e.NewMetricSet("RabbitmqQueueGroupSample", metricAttribs...)
+	assert.NoError(t, ms.MarshalMetrics(group))
+	assert.Equal(t, OtherQueuesGroup, ms.Metrics["queueGroup.name"])
+	assert.Equal(t, QueueGroupTypeOther, ms.Metrics["queueGroup.type"])
+	assert.Equal(t, map[string]interface{}{"queueGroup.name": OtherQueuesGroup, "queueGroup.type": QueueGroupTypeOther}, group.SampleAttributes())
+	assert.Equal(t, float64(3), ms.Metrics["queueGroup.queues"])
+	assert.Equal(t, float64(20), ms.Metrics["queueGroup.totalMessages"])
+	assert.Equal(t, float64(4), ms.Metrics["queueGroup.consumers"])
+	assert.Equal(t, float64(3), ms.Metrics["queueGroup.messagesPublishedPerSecond"])
+	assert.Equal(t, float64(0), ms.Metrics["queueGroup.messagesAcknowledgedPerSecond"])
+}
+
+func TestQueueGroupData_Summarize(t *testing.T) {
+	group := &QueueGroupData{Vhost: "vhost1", Name: AllQueuesGroup}
+	for i := int64(1); i <= 40; i++ {
+		messages, unacked, consumers := i, 2*i, i%3
+		group.Add(&QueueData{Name: "queue", Vhost: "vhost1", Messages: &messages, MessagesUnacknowledged: &unacked, Consumers: &consumers})
+	}
+	group.Summarize()
+	assert.Equal(t, int64(820), group.Messages)
+	assert.Equal(t, int64(40), group.MessagesMax)
+	assert.Equal(t, int64(38), group.MessagesP95)
+	assert.Equal(t, int64(80), group.MessagesUnacknowledgedMax)
+	assert.Equal(t, int64(76), group.MessagesUnacknowledgedP95)
+	assert.Equal(t, int64(2), group.ConsumersMax)
+	assert.Equal(t, int64(2), group.ConsumersP95)
+}
+
+func Test_maxAndP95(t *testing.T) {
+	maxValue, p95 := maxAndP95(nil)
+	assert.Equal(t, int64(0), maxValue)
+	assert.Equal(t, int64(0), p95)
+
+	maxValue, p95 = maxAndP95([]int64{7})
+	assert.Equal(t, int64(7), maxValue)
+	assert.Equal(t, int64(7), p95)
+
+	values := []int64{5, 1, 9, 3}
+	maxValue, p95 = maxAndP95(values)
+	assert.Equal(t, int64(9), maxValue)
+	assert.Equal(t, int64(9), p95)
+	assert.Equal(t, []int64{5, 1, 9, 3}, values, "the values are not sorted in place")
+}
@@ -0,0 +1,57 @@
+package data
+
+import (
+	"github.com/newrelic/infra-integrations-sdk/v3/persist"
+)
+
+// backlogSnapshot is what is kept in the store to compute the backlog growth in the next run
+type backlogSnapshot struct {
+	Timestamp int64 `json:"timestamp"`
+	Messages  int64 `json:"messages"`
+}
+
+// ComputeQueueTrends derives the backlog trend of the queues: how fast the backlog grew since the previous run, the net
+// inflow (publish rate minus consume rate) and the estimated time to drain the backlog at the current rates, which is
+// left out while the queue isn't draining. It must run after ComputeMissingRates so it can use the local rates. The store
+// keeps an entry per queue, so it's only called with the queues reported.
+func ComputeQueueTrends(store persist.Storer, dataItems ...EntityData) {
+	timestamp := now().Unix()
+	for _, dataItem := range dataItems {
+		queue, ok := dataItem.(*QueueData)
+		if !ok || queue.Messages == nil {
+			continue
+		}
+
+		key := "backlog/" + joinVhostName(queue.Vhost, queue.Name)
+		var previous backlogSnapshot
+		if _, err := store.Get(key, &previous); err == nil && previous.Timestamp < timestamp {
+			growth := float64(*queue.Messages-previous.Messages) / float64(timestamp-previous.Timestamp)
+			queue.BacklogGrowth = &growth
+		}
+		store.Set(key, backlogSnapshot{Timestamp: timestamp, Messages: *queue.Messages})
+
+		publishRate := queue.MessageStats.PublishDetails.Rate
+		consumeRate := queue.consumeRate()
+		if publishRate == nil || consumeRate == nil {
+			continue
+		}
+		netInflow := *publishRate - *consumeRate
+		queue.NetInflow = &netInflow
+		if *queue.Messages == 0 {
+			timeToDrain := 0.0
+			queue.TimeToDrain = &timeToDrain
+		} else if netInflow < 0 {
+			timeToDrain := float64(*queue.Messages) / -netInflow
+			queue.TimeToDrain = &timeToDrain
+		}
+	}
+}
+
+// consumeRate returns the rate at which messages leave the queue: acknowledgements, or deliveries when consumers
+// don't acknowledge
+func (q *QueueData) consumeRate() *float64 {
+	if q.MessageStats.AckDetails.Rate != nil {
+		return q.MessageStats.AckDetails.Rate
+	}
+	return q.MessageStats.DeliverGetDetails.Rate
+}
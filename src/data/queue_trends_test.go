@@ -0,0 +1,51 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/persist"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeQueueTrends(t *testing.T) {
+	defer func() {
+		now = time.Now
+	}()
+	store := persist.NewInMemoryStore()
+	newQueue := func(messages int64, publishRate, ackRate *float64) *QueueData {
+		q := &QueueData{Name: "queue1", Vhost: "vhost1", Messages: &messages}
+		q.MessageStats.PublishDetails.Rate = publishRate
+		q.MessageStats.AckDetails.Rate = ackRate
+		return q
+	}
+	rate := func(value float64) *float64 { return &value }
+
+	now = func() time.Time { return time.Unix(1000, 0) }
+	queue := newQueue(1000, rate(10), rate(30))
+	ComputeQueueTrends(store, queue, &NodeData{Name: "node1"})
+	assert.Nil(t, queue.BacklogGrowth, "the first run has no previous backlog")
+	assert.Equal(t, -20.0, *queue.NetInflow)
+	assert.Equal(t, 50.0, *queue.TimeToDrain)
+
+	now = func() time.Time { return time.Unix(1010, 0) }
+	queue = newQueue(1500, rate(60), nil)
+	queue.MessageStats.DeliverGetDetails.Rate = rate(10)
+	ComputeQueueTrends(store, queue)
+	assert.Equal(t, 50.0, *queue.BacklogGrowth)
+	assert.Equal(t, 50.0, *queue.NetInflow, "deliveries are the consume rate when there are no acks")
+	assert.Nil(t, queue.TimeToDrain, "a growing backlog never drains")
+
+	now = func() time.Time { return time.Unix(1020, 0) }
+	queue = newQueue(0, rate(1), rate(1))
+	ComputeQueueTrends(store, queue)
+	assert.Equal(t, -150.0, *queue.BacklogGrowth)
+	assert.Equal(t, 0.0, *queue.TimeToDrain)
+
+	now = func() time.Time { return time.Unix(1030, 0) }
+	queue = newQueue(10, nil, nil)
+	ComputeQueueTrends(store, queue)
+	assert.Equal(t, 1.0, *queue.BacklogGrowth)
+	assert.Nil(t, queue.NetInflow, "the net inflow needs the message rates")
+	assert.Nil(t, queue.TimeToDrain)
+}
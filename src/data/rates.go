@@ -0,0 +1,89 @@
+package data
+
+import (
+	"time"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/log"
+	"github.com/newrelic/infra-integrations-sdk/v3/persist"
+)
+
+// now is replaced in tests
+var now = time.Now
+
+// rateCounter is a cumulative counter from message_stats along with the rate the Management API computes for it
+type rateCounter struct {
+	name    string
+	counter *int64
+	rate    **float64
+}
+
+// counterSnapshot is what is kept in the store to compute the rates in the next run
+type counterSnapshot struct {
+	Timestamp int64            `json:"timestamp"`
+	Counters  map[string]int64 `json:"counters"`
+}
+
+// rateCounters returns the counters whose rates can be computed locally for the entity
+func rateCounters(dataItem EntityData) []rateCounter {
+	switch v := dataItem.(type) {
+	case *QueueData:
+		stats := &v.MessageStats
+		return []rateCounter{
+			{"publish", stats.Publish, &stats.PublishDetails.Rate},
+			{"ack", stats.Ack, &stats.AckDetails.Rate},
+			{"deliver", stats.Deliver, &stats.DeliverDetails.Rate},
+			{"deliver_get", stats.DeliverGet, &stats.DeliverGetDetails.Rate},
+			{"redeliver", stats.Redeliver, &stats.RedeliverDetails.Rate},
+		}
+	case *ExchangeData:
+		stats := &v.MessageStats
+		return []rateCounter{
+			{"publish_in", stats.PublishIn, &stats.PublishInDetails.Rate},
+			{"publish_out", stats.PublishOut, &stats.PublishOutDetails.Rate},
+		}
+	}
+	return nil
+}
+
+// ComputeMissingRates fills the message rates that the Management API doesn't report, as it happens when
+// management.rates_mode is none, from the difference between the counters of this run and the previous one.
+// The counters are kept in the store per entity. A counter lower than in the previous run means the entity was
+// recreated, so its rate is left out until the next run.
+func ComputeMissingRates(store persist.Storer, dataItems ...EntityData) {
+	timestamp := now().Unix()
+	for _, dataItem := range dataItems {
+		var missing []rateCounter
+		for _, c := range rateCounters(dataItem) {
+			if c.counter != nil && *c.rate == nil {
+				missing = append(missing, c)
+			}
+		}
+		if len(missing) == 0 {
+			continue
+		}
+
+		key := "rates/" + dataItem.EntityType() + "/" + joinVhostName(dataItem.EntityVhost(), dataItem.EntityName())
+		var previous counterSnapshot
+		_, err := store.Get(key, &previous)
+		hasPrevious := err == nil && previous.Timestamp < timestamp
+
+		current := counterSnapshot{Timestamp: timestamp, Counters: make(map[string]int64, len(missing))}
+		for _, c := range missing {
+			current.Counters[c.name] = *c.counter
+			if !hasPrevious {
+				continue
+			}
+			previousValue, ok := previous.Counters[c.name]
+			if !ok {
+				continue
+			}
+			if *c.counter < previousValue {
+				log.Debug("Counter [%s] of %s [%s] was reset, skipping its rate", c.name, dataItem.EntityType(), dataItem.EntityName())
+				continue
+			}
+			rate := float64(*c.counter-previousValue) / float64(timestamp-previous.Timestamp)
+			*c.rate = &rate
+		}
+		store.Set(key, current)
+	}
+}
@@ -0,0 +1,70 @@
+package data
+
+import (
+	"testing"
+	"time"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/persist"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComputeMissingRates(t *testing.T) {
+	defer func() {
+		now = time.Now
+	}()
+	store := persist.NewInMemoryStore()
+	apiRate := 2.5
+	newQueue := func(publish, ack int64) *QueueData {
+		q := &QueueData{Name: "queue1", Vhost: "vhost1"}
+		q.MessageStats.Publish = &publish
+		q.MessageStats.Ack = &ack
+		return q
+	}
+
+	now = func() time.Time { return time.Unix(1000, 0) }
+	queue := newQueue(100, 50)
+	ComputeMissingRates(store, queue)
+	assert.Nil(t, queue.MessageStats.PublishDetails.Rate, "the first run has no previous counters")
+	assert.Nil(t, queue.MessageStats.AckDetails.Rate)
+
+	now = func() time.Time { return time.Unix(1010, 0) }
+	queue = newQueue(150, 60)
+	queue.MessageStats.AckDetails.Rate = &apiRate
+	ComputeMissingRates(store, queue)
+	assert.Equal(t, 5.0, *queue.MessageStats.PublishDetails.Rate)
+	assert.Equal(t, apiRate, *queue.MessageStats.AckDetails.Rate, "rates reported by the Management API are kept")
+	assert.Nil(t, queue.MessageStats.DeliverDetails.Rate, "rates of missing counters are not computed")
+
+	now = func() time.Time { return time.Unix(1020, 0) }
+	queue = newQueue(20, 70)
+	ComputeMissingRates(store, queue)
+	assert.Nil(t, queue.MessageStats.PublishDetails.Rate, "the rate of a reset counter is left out")
+	assert.Nil(t, queue.MessageStats.AckDetails.Rate, "the ack counter wasn't stored when its rate was reported")
+
+	now = func() time.Time { return time.Unix(1030, 0) }
+	queue = newQueue(40, 80)
+	ComputeMissingRates(store, queue)
+	assert.Equal(t, 2.0, *queue.MessageStats.PublishDetails.Rate)
+	assert.Equal(t, 1.0, *queue.MessageStats.AckDetails.Rate)
+}
+
+func TestComputeMissingRates_Exchange(t *testing.T) {
+	defer func() {
+		now = time.Now
+	}()
+	store := persist.NewInMemoryStore()
+	newExchange := func(publishIn int64) *ExchangeData {
+		e := &ExchangeData{Name: "exchange1", Vhost: "vhost1"}
+		e.MessageStats.PublishIn = &publishIn
+		return e
+	}
+
+	now = func() time.Time { return time.Unix(1000, 0) }
+	ComputeMissingRates(store, newExchange(10), &NodeData{Name: "node1"})
+
+	now = func() time.Time { return time.Unix(1004, 0) }
+	exchange := newExchange(30)
+	ComputeMissingRates(store, exchange)
+	assert.Equal(t, 5.0, *exchange.MessageStats.PublishInDetails.Rate)
+	assert.Nil(t, exchange.MessageStats.PublishOutDetails.Rate)
+}
@@ -0,0 +1,83 @@
+package data
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/newrelic/nri-rabbitmq/src/args"
+)
+
+// RedactedValue replaces the values of secrets so they are never reported
+const RedactedValue = "[redacted]"
+
+// uriCredentialsRegex matches the password in URIs embedded within other text, e.g. in Erlang terms
+var uriCredentialsRegex = regexp.MustCompile(`(?i)\b([a-z][a-z0-9+.-]*://[^\s:/@"',\[\]{}]*:)[^\s/@"',\[\]{}]*@`)
+
+// RedactInventoryValue returns the value to report for the inventory key, redacting it entirely if the key is a
+// secret or the passwords of the URIs within it otherwise
+func RedactInventoryValue(key string, value interface{}) interface{} {
+	if args.GlobalArgs.IsRedactedKey(key) {
+		return RedactedValue
+	}
+	if s, ok := value.(string); ok {
+		return redactURICredentials(s)
+	}
+	return value
+}
+
+// redactURICredentials replaces the passwords of the URIs within the text, such as the errors of shovels and federation links
+func redactURICredentials(text string) string {
+	return uriCredentialsRegex.ReplaceAllString(text, "${1}xxxxx@")
+}
+
+// redactErrorText redacts the URI passwords of an error reported by the management API
+func redactErrorText(text *string) *string {
+	if text == nil {
+		return nil
+	}
+	redacted := redactURICredentials(*text)
+	return &redacted
+}
+
+// redactURI returns the URI with its password and secret query parameters replaced, so it can be safely reported
+func redactURI(rawURI string) string {
+	u, err := url.Parse(rawURI)
+	if err != nil {
+		return RedactedValue
+	}
+	if u.RawQuery != "" {
+		query := u.Query()
+		redacted := false
+		for param := range query {
+			if args.GlobalArgs.IsRedactedKey(param) {
+				query.Set(param, RedactedValue)
+				redacted = true
+			}
+		}
+		if redacted {
+			u.RawQuery = query.Encode()
+		}
+	}
+	return u.Redacted()
+}
+
+// redactURIs redacts a URI value from the management API, which can be either a single URI or a list of them
+func redactURIs(value interface{}) *string {
+	var uris []string
+	switch v := value.(type) {
+	case string:
+		uris = append(uris, redactURI(v))
+	case []interface{}:
+		for _, item := range v {
+			if uri, ok := item.(string); ok {
+				uris = append(uris, redactURI(uri))
+			}
+		}
+	}
+	if len(uris) == 0 {
+		return nil
+	}
+	redacted := strings.Join(uris, ", ")
+	return &redacted
+}
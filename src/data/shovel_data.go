@@ -0,0 +1,81 @@
+package data
+
+import (
+	"encoding/json"
+
+	"github.com/newrelic/nri-rabbitmq/src/data/consts"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/data/attribute"
+	"github.com/newrelic/infra-integrations-sdk/v3/integration"
+)
+
+// ShovelData is the representation of the shovels endpoint
+type ShovelData struct {
+	Name           string
+	Vhost          string
+	Node           string  `metric_name:"shovel.node" source_type:"attribute"`
+	Type           string  `metric_name:"shovel.type" source_type:"attribute"`
+	State          string  `metric_name:"shovel.state" source_type:"attribute"`
+	Running        int     `json:"-" metric_name:"shovel.running" source_type:"gauge"`
+	SourceURI      *string `json:"-" metric_name:"shovel.sourceUri" source_type:"attribute"`
+	DestinationURI *string `json:"-" metric_name:"shovel.destinationUri" source_type:"attribute"`
+	Reason         *string `metric_name:"shovel.error" source_type:"attribute"`
+}
+
+// GetEntity creates an integration.Entity for this ShovelData
+func (s *ShovelData) GetEntity(integration *integration.Integration, clusterName string) (*integration.Entity, []attribute.Attribute, error) {
+	return CreateEntity(integration, s.Name, consts.ShovelType, s.Vhost, clusterName)
+}
+
+// EntityType returns the type of this entity
+func (s *ShovelData) EntityType() string {
+	return consts.ShovelType
+}
+
+// EntityName returns the main name of this entity
+func (s *ShovelData) EntityName() string {
+	return s.Name
+}
+
+// EntityVhost returns the vhost of this entity
+func (s *ShovelData) EntityVhost() string {
+	return s.Vhost
+}
+
+// IsRunning returns true if the shovel is in the running state
+func (s *ShovelData) IsRunning() bool {
+	return s.State == LinkRunning
+}
+
+// LinkState returns the state reported for the shovel
+func (s *ShovelData) LinkState() string {
+	return s.State
+}
+
+// LinkError returns the error reported for the shovel, if any
+func (s *ShovelData) LinkError() string {
+	if s.Reason == nil {
+		return ""
+	}
+	return redactURICredentials(*s.Reason)
+}
+
+// UnmarshalJSON handles custom JSON Unmarshaling in order to redact the credentials from the shovel URIs and its error
+func (s *ShovelData) UnmarshalJSON(data []byte) error {
+	type Alias ShovelData
+	aux := &struct {
+		SourceURI      interface{} `json:"src_uri"`
+		DestinationURI interface{} `json:"dest_uri"`
+		*Alias
+	}{
+		Alias: (*Alias)(s),
+	}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+	s.SourceURI = redactURIs(aux.SourceURI)
+	s.DestinationURI = redactURIs(aux.DestinationURI)
+	s.Reason = redactErrorText(s.Reason)
+	s.Running = ConvertBoolToInt(s.IsRunning())
+	return nil
+}
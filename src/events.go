@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/newrelic/nri-rabbitmq/src/data"
+	"github.com/newrelic/nri-rabbitmq/src/data/consts"
+	"github.com/newrelic/nri-rabbitmq/src/state"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/data/event"
+	"github.com/newrelic/infra-integrations-sdk/v3/integration"
+	"github.com/newrelic/infra-integrations-sdk/v3/log"
+	"github.com/newrelic/infra-integrations-sdk/v3/persist"
+)
+
+// linkStateTest adds events when a shovel or federation link leaves the running state and when it recovers
+func linkStateTest(rabbitmqIntegration *integration.Integration, store persist.Storer, links []data.LinkData, clusterName string) {
+	if rabbitmqIntegration != nil {
+		for _, link := range links {
+			key := fmt.Sprintf("link/%s/%s/%s", link.EntityType(), link.EntityVhost(), link.EntityName())
+			change, outage := state.Check(store, key, !link.IsRunning())
+			if change == state.NoTransition {
+				continue
+			}
+
+			e, _, err := link.GetEntity(rabbitmqIntegration, clusterName)
+			if err != nil {
+				log.Error("Error creating %s entity [%s]: %v", link.EntityType(), link.EntityName(), err)
+				continue
+			}
+
+			// Don't add events for the entity if we are skipping its collection
+			if e != nil {
+				description := fmt.Sprintf("Response is [%s] for %s [%s] in vhost [%s]", link.LinkState(), link.EntityType(), link.EntityName(), link.EntityVhost())
+				if change == state.Recovered {
+					description = fmt.Sprintf("%s after not running for %s", description, outage)
+				} else if reason := link.LinkError(); reason != "" {
+					description = fmt.Sprintf("%s: %s", description, reason)
+				}
+				exitIfError(e.AddEvent(event.New(description, "integration")), "Error adding event: %v")
+			}
+			state.Record(store, key, change)
+		}
+	}
+}
+
+// maxBlockedConnections is the maximum number of blocked connections listed in an alarm event
+const maxBlockedConnections = 10
+
+// nodeAlarm is a resource alarm reported by the nodes endpoint
+type nodeAlarm struct {
+	name      string
+	raised    func(node *data.NodeData) *bool
+	usage     func(node *data.NodeData) string
+	stateName string
+}
+
+var nodeAlarms = []nodeAlarm{
+	{
+		name:   "Memory",
+		raised: func(node *data.NodeData) *bool { return node.MemoryAlarm },
+		usage: func(node *data.NodeData) string {
+			return formatUsage("memory used", node.MemoryUsed, "mem_limit", node.MemoryLimit)
+		},
+		stateName: "memoryAlarm",
+	},
+	{
+		name:   "Disk",
+		raised: func(node *data.NodeData) *bool { return node.DiskAlarm },
+		usage: func(node *data.NodeData) string {
+			return formatUsage("disk free", node.DiskFreeSpace, "disk_free_limit", node.DiskFreeLimit)
+		},
+		stateName: "diskAlarm",
+	},
+}
+
+// alarmTest adds events when a node enters or leaves a memory or disk alarm. While an alarm is in effect, publishers
+// are blocked across the whole cluster, so the blocked connections are listed when the alarm is raised.
+func alarmTest(rabbitmqIntegration *integration.Integration, store persist.Storer, nodes []*data.NodeData, connections []*data.ConnectionData, clusterName string) {
+	if rabbitmqIntegration == nil {
+		return
+	}
+	for _, node := range nodes {
+		for _, alarm := range nodeAlarms {
+			raised := alarm.raised(node)
+			if raised == nil {
+				continue
+			}
+			key := alarm.stateName + "/" + node.Name
+			change, duration := state.Check(store, key, *raised)
+			if change == state.NoTransition {
+				continue
+			}
+
+			e, _, err := node.GetEntity(rabbitmqIntegration, clusterName)
+			if err != nil {
+				log.Error("Error creating node entity [%s]: %v", node.Name, err)
+				continue
+			}
+
+			// Don't add events for the entity if we are skipping its collection
+			if e != nil {
+				description := fmt.Sprintf("%s alarm raised on node [%s]: %s, %s", alarm.name, node.Name, alarm.usage(node), describeBlockedConnections(connections))
+				if change == state.Recovered {
+					description = fmt.Sprintf("%s alarm cleared on node [%s] after %s: %s", alarm.name, node.Name, duration, alarm.usage(node))
+				}
+				exitIfError(e.AddEvent(event.New(description, "integration")), "Error adding event: %v")
+			}
+			state.Record(store, key, change)
+		}
+	}
+}
+
+func formatUsage(usageName string, usage *int64, limitName string, limit *int64) string {
+	format := func(value *int64) string {
+		if value == nil {
+			return "unknown"
+		}
+		return fmt.Sprintf("%d bytes", *value)
+	}
+	return fmt.Sprintf("%s %s, %s %s", usageName, format(usage), limitName, format(limit))
+}
+
+// describeBlockedConnections lists the blocked connections, up to maxBlockedConnections of them
+func describeBlockedConnections(connections []*data.ConnectionData) string {
+	var blocked []string
+	for _, connection := range connections {
+		if connection.State == data.ConnectionBlocked {
+			blocked = append(blocked, fmt.Sprintf("%s (user [%s], vhost [%s])", connection.Name, connection.User, connection.Vhost))
+		}
+	}
+	if len(blocked) == 0 {
+		return "no connections blocked"
+	}
+	description := fmt.Sprintf("%d connections blocked: %s", len(blocked), strings.Join(blocked[:min(len(blocked), maxBlockedConnections)], ", "))
+	if len(blocked) > maxBlockedConnections {
+		description += fmt.Sprintf(" and %d more", len(blocked)-maxBlockedConnections)
+	}
+	return description
+}
+
+// partitionTest adds events when a node starts or stops seeing a network partition, naming the nodes it can't reach
+func partitionTest(rabbitmqIntegration *integration.Integration, store persist.Storer, nodes []*data.NodeData, partitionHandling, clusterName string) {
+	if rabbitmqIntegration == nil {
+		return
+	}
+	for _, node := range nodes {
+		key := "partition/" + node.Name
+		change, duration := state.Check(store, key, node.Partitions > 0)
+		if change == state.NoTransition {
+			continue
+		}
+
+		e, _, err := node.GetEntity(rabbitmqIntegration, clusterName)
+		if err != nil {
+			log.Error("Error creating node entity [%s]: %v", node.Name, err)
+			continue
+		}
+
+		// Don't add events for the entity if we are skipping its collection
+		if e != nil {
+			description := fmt.Sprintf("Network partition seen by node [%s]: partitioned from [%s]", node.Name, strings.Join(node.PartitionPeers, ", "))
+			if change == state.Recovered {
+				description = fmt.Sprintf("Network partition seen by node [%s] ended after %s", node.Name, duration)
+			}
+			if partitionHandling != "" {
+				description = fmt.Sprintf("%s, cluster_partition_handling is [%s]", description, partitionHandling)
+			}
+			exitIfError(e.AddEvent(event.New(description, "integration")), "Error adding event: %v")
+		}
+		state.Record(store, key, change)
+	}
+}
+
+// membershipTest compares the cluster nodes with the ones seen in the previous run, adding events to the cluster
+// entity for the nodes that joined or left. A node leaving while another one joins on the same host is reported as renamed.
+func membershipTest(rabbitmqIntegration *integration.Integration, store persist.Storer, nodes []*data.NodeData, clusterName string) {
+	if rabbitmqIntegration == nil || clusterName == "" {
+		return
+	}
+	key := "members/" + clusterName
+	members := make([]string, len(nodes))
+	for i, node := range nodes {
+		members[i] = node.Name
+	}
+
+	added, removed, found := state.CheckMembers(store, key, members)
+	if !found {
+		// nothing to compare against in the first run
+		state.RecordMembers(store, key, members)
+		return
+	}
+
+	var descriptions []string
+	for _, oldName := range removed {
+		if i := findSameHost(oldName, added); i >= 0 {
+			descriptions = append(descriptions, fmt.Sprintf("Node [%s] was renamed to [%s] in cluster [%s]", oldName, added[i], clusterName))
+			added = append(added[:i], added[i+1:]...)
+			continue
+		}
+		descriptions = append(descriptions, fmt.Sprintf("Node [%s] left cluster [%s]", oldName, clusterName))
+	}
+	for _, newName := range added {
+		descriptions = append(descriptions, fmt.Sprintf("Node [%s] joined cluster [%s]", newName, clusterName))
+	}
+	if len(descriptions) == 0 {
+		state.RecordMembers(store, key, members)
+		return
+	}
+
+	e, _, err := data.CreateEntity(rabbitmqIntegration, clusterName, consts.ClusterType, "", clusterName)
+	if err != nil {
+		log.Error("Error creating cluster entity [%s]: %v", clusterName, err)
+		return
+	}
+	if e != nil {
+		for _, description := range descriptions {
+			exitIfError(e.AddEvent(event.New(description, "integration")), "Error adding event: %v")
+		}
+	}
+	state.RecordMembers(store, key, members)
+}
+
+// findSameHost returns the index of the node running on the same host as nodeName, or -1 if there's none
+func findSameHost(nodeName string, names []string) int {
+	at := strings.LastIndexByte(nodeName, '@')
+	if at < 0 {
+		return -1
+	}
+	for i, name := range names {
+		if strings.HasSuffix(name, nodeName[at:]) {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,90 @@
+package inventory
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+
+	"github.com/newrelic/nri-rabbitmq/src/args"
+	"github.com/newrelic/nri-rabbitmq/src/data"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/integration"
+	"github.com/newrelic/infra-integrations-sdk/v3/log"
+)
+
+const effectiveConfigCategory = "effective_config"
+
+// collectEffectiveConfig reports the application environment of the node, which includes the values set through
+// environment variables and defaults, flagging the keys whose effective value differs from the configuration files
+func collectEffectiveConfig(localNode *integration.Entity, nodeName string, fileConfig map[inventoryKey]configValue) {
+	effectiveConfig, err := getEffectiveConfig(nodeName)
+	if err != nil {
+		log.Error("Error collecting the effective configuration: %v", err)
+		return
+	}
+
+	for k, v := range effectiveConfig {
+		data.SetInventoryItem(localNode, k.category, k.key, v)
+		if fileValue, ok := findFileValue(k.key, fileConfig); ok && fileValue.value != v {
+			setInventoryField(localNode, k, "differs_from_file", 1)
+			setInventoryField(localNode, k, "file_value", data.RedactInventoryValue(k.key, fileValue.value))
+			setInventoryField(localNode, k, "source", fileValue.source)
+		}
+	}
+}
+
+// getEffectiveConfig parses the output of 'rabbitmq-diagnostics environment', either from EffectiveConfigPath or by running it
+func getEffectiveConfig(nodeName string) (map[inventoryKey]string, error) {
+	var output []byte
+	var err error
+	if path := args.GlobalArgs.EffectiveConfigPath; path != "" {
+		output, err = readFile(path)
+	} else {
+		output, err = rabbitmqCommand("rabbitmq-diagnostics", "-q", "-n", nodeName, "environment").Output()
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// skip any banner printed before the environment
+	if start := bytes.IndexByte(output, '['); start > 0 {
+		output = output[start:]
+	}
+	environment, err := parseErlangConfigInventory(bytes.NewReader(output))
+	if err != nil {
+		return nil, err
+	}
+
+	values := make(map[inventoryKey]string, len(environment))
+	for k, v := range environment {
+		values[inventoryKey{effectiveConfigCategory, k.key}] = v
+	}
+	return values, nil
+}
+
+// findFileValue returns the value read from the configuration files for an application environment key. Keys from
+// Erlang term files have the same name, while rabbitmq.conf keys match when they are named after the rabbit application key
+func findFileValue(key string, fileConfig map[inventoryKey]configValue) (configValue, bool) {
+	if value, ok := fileConfig[inventoryKey{"config", key}]; ok && isErlangConfig(value.source) {
+		return value, true
+	}
+	if rabbitKey := strings.TrimPrefix(key, "rabbit."); rabbitKey != key {
+		if value, ok := fileConfig[inventoryKey{"config", rabbitKey}]; ok && !isErlangConfig(value.source) {
+			return value, true
+		}
+	}
+	return configValue{}, false
+}
+
+func readFile(path string) ([]byte, error) {
+	file, err := osOpen(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Error("Error closing file [%s]: %v", path, err)
+		}
+	}()
+	return ioutil.ReadAll(file)
+}
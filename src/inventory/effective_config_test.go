@@ -0,0 +1,126 @@
+package inventory
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/newrelic/nri-rabbitmq/src/args"
+	"github.com/newrelic/nri-rabbitmq/src/testutils"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testEffectiveConfigPath = filepath.Join("testdata", "environment.txt")
+
+func Test_getEffectiveConfig(t *testing.T) {
+	args.GlobalArgs = args.RabbitMQArguments{
+		EffectiveConfigPath: testEffectiveConfigPath,
+	}
+
+	config, err := getEffectiveConfig(expectedNodeName)
+	require.NoError(t, err)
+	expected := map[string]string{
+		"kernel.inet_dist_listen_max":       "25672",
+		"kernel.logger_level":               "notice",
+		"kernel.net_ticktime":               "60",
+		"rabbit.cluster_partition_handling": "autoheal",
+		"rabbit.default_user":               "guest",
+		"rabbit.heartbeat":                  "60",
+		"rabbit.ssl_options.verify":         "verify_none",
+		"rabbit.vm_memory_high_watermark":   "{relative,0.4}",
+		"rabbit.log_handler":                "#Fun<rabbit_prelaunch.0.12345>",
+		"rabbit.connection_pid":             "<0.123.0>",
+
+		"rabbitmq_auth_backend_ldap.dn_lookup_bind": `{"cn=admin,dc=example,dc=com","secret"}`,
+	}
+	assert.Equal(t, len(expected), len(config))
+	for k, v := range expected {
+		assert.Equal(t, v, config[inventoryKey{effectiveConfigCategory, k}], k)
+	}
+
+	args.GlobalArgs.EffectiveConfigPath = filepath.Join("testdata", "not-found.txt")
+	_, err = getEffectiveConfig(expectedNodeName)
+	assert.Error(t, err)
+}
+
+func Test_getEffectiveConfig_RealisticDump(t *testing.T) {
+	args.GlobalArgs = args.RabbitMQArguments{
+		EffectiveConfigPath: filepath.Join("testdata", "environment-otp26.txt"),
+	}
+
+	config, err := getEffectiveConfig(expectedNodeName)
+	require.NoError(t, err, "unknown terms must not fail the whole environment")
+	expected := map[string]string{
+		"kernel.logger_level":                          "info",
+		"kernel.net_ticktime":                          "60",
+		"rabbit.cluster_partition_handling":            "pause_minority",
+		"rabbit.credit_flow_default_credit":            "{400,200}",
+		"rabbit.default_user":                          "guest",
+		"rabbit.product_banner":                        "RabbitMQ on Erlang/OTP",
+		"rabbit.vm_memory_high_watermark":              "0.4",
+		"rabbit.vm_memory_high_watermark_paging_ratio": "-1.5e-3",
+		"rabbit.tcp_listen_options.linger":             "{true,0}",
+		"rabbit.log.console.level":                     "info",
+		"ra.wal_max_size_bytes":                        "536870912",
+	}
+	for k, v := range expected {
+		assert.Equal(t, v, config[inventoryKey{effectiveConfigCategory, k}], k)
+	}
+	assert.Contains(t, config[inventoryKey{effectiveConfigCategory, "kernel.logger"}], "fun logger_filters:progress/2")
+}
+
+func Test_getEffectiveConfig_Command(t *testing.T) {
+	args.GlobalArgs = args.RabbitMQArguments{}
+	prevExec := execCommand
+	execCommand = fakeExecCommand
+	os.Setenv("GO_WANT_HELPER_PROCESS", "1")
+	defer func() {
+		execCommand = prevExec
+		os.Unsetenv("GO_WANT_HELPER_PROCESS")
+	}()
+
+	config, err := getEffectiveConfig(expectedNodeName)
+	require.NoError(t, err)
+	assert.Equal(t, "autoheal", config[inventoryKey{effectiveConfigCategory, "rabbit.cluster_partition_handling"}])
+
+	_, err = getEffectiveConfig("unknown-node")
+	assert.Error(t, err)
+}
+
+func Test_collectEffectiveConfig(t *testing.T) {
+	args.GlobalArgs = args.RabbitMQArguments{
+		EffectiveConfigPath: testEffectiveConfigPath,
+	}
+	_, e := testutils.GetTestingEntity(t)
+	fileConfig := map[inventoryKey]configValue{
+		{"config", "cluster_partition_handling"}: {value: "pause_minority", source: "rabbitmq.conf"},
+		{"config", "heartbeat"}:                  {value: "60", source: "rabbitmq.conf"},
+		{"config", "rabbit.ssl_options.verify"}:  {value: "verify_peer", source: "advanced.config"},
+		{"config", "kernel.logger_level"}:        {value: "debug", source: "rabbitmq.conf"},
+	}
+
+	collectEffectiveConfig(e, expectedNodeName, fileConfig)
+
+	item, ok := e.Inventory.Item("effective_config/rabbit.cluster_partition_handling")
+	require.True(t, ok)
+	assert.Equal(t, "autoheal", item["value"])
+	assert.Equal(t, 1, item["differs_from_file"])
+	assert.Equal(t, "pause_minority", item["file_value"])
+	assert.Equal(t, "rabbitmq.conf", item["source"])
+
+	item, ok = e.Inventory.Item("effective_config/rabbit.ssl_options.verify")
+	require.True(t, ok)
+	assert.Equal(t, "verify_none", item["value"])
+	assert.Equal(t, 1, item["differs_from_file"])
+	assert.Equal(t, "advanced.config", item["source"])
+
+	item, ok = e.Inventory.Item("effective_config/rabbit.heartbeat")
+	require.True(t, ok)
+	assert.NotContains(t, item, "differs_from_file")
+
+	item, ok = e.Inventory.Item("effective_config/kernel.logger_level")
+	require.True(t, ok)
+	assert.NotContains(t, item, "differs_from_file", "rabbitmq.conf keys only match the rabbit application")
+}
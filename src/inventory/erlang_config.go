@@ -0,0 +1,423 @@
+package inventory
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"unicode"
+)
+
+// erlangAtom is an Erlang atom, kept apart from strings so proplist keys can be told from values
+type erlangAtom string
+
+// erlangTuple is an Erlang tuple
+type erlangTuple []interface{}
+
+// erlangTerm is a number or any other literal that is reported as it was written
+type erlangTerm string
+
+// parseErlangConfigInventory parses an Erlang term configuration file (advanced.config or the legacy rabbitmq.config) and
+// flattens the application proplists into inventory keys, e.g. [{rabbit, [{ssl_options, [{verify, verify_peer}]}]}]
+// becomes rabbit.ssl_options.verify = verify_peer
+func parseErlangConfigInventory(reader io.Reader) (map[inventoryKey]string, error) {
+	content, err := ioutil.ReadAll(reader)
+	if err != nil {
+		return nil, err
+	}
+	p := &erlangParser{input: []rune(string(content))}
+	if p.skipWhitespace(); p.eof() {
+		return map[inventoryKey]string{}, nil
+	}
+	term, err := p.parseTerm()
+	if err != nil {
+		return nil, err
+	}
+	if p.skipWhitespace(); !p.eof() && p.peek() == '.' {
+		p.pos++
+	}
+	if p.skipWhitespace(); !p.eof() {
+		return nil, p.errorf("unexpected content after the configuration term")
+	}
+
+	values := make(map[inventoryKey]string)
+	list, ok := term.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("the configuration must be a list of application tuples")
+	}
+	flattenErlangTerm(values, "", list)
+	return values, nil
+}
+
+// flattenErlangTerm adds the term to values, walking down proplists and joining their keys with a dot
+func flattenErlangTerm(values map[inventoryKey]string, prefix string, term interface{}) {
+	if list, ok := term.([]interface{}); ok && isProplist(list) {
+		for _, item := range list {
+			key, value := proplistEntry(item)
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			flattenErlangTerm(values, key, value)
+		}
+		return
+	}
+	if prefix != "" {
+		values[inventoryKey{"config", prefix}] = formatErlangTerm(term, true)
+	}
+}
+
+// isProplist returns true for lists made of {key, value} tuples, where bare atoms are shorthand for {atom, true}
+func isProplist(list []interface{}) bool {
+	hasTuples := false
+	for _, item := range list {
+		switch v := item.(type) {
+		case erlangAtom:
+		case erlangTuple:
+			if len(v) != 2 {
+				return false
+			}
+			if _, ok := v[0].(erlangAtom); !ok {
+				return false
+			}
+			hasTuples = true
+		default:
+			return false
+		}
+	}
+	return hasTuples
+}
+
+func proplistEntry(item interface{}) (string, interface{}) {
+	if atom, ok := item.(erlangAtom); ok {
+		return string(atom), erlangAtom("true")
+	}
+	tuple := item.(erlangTuple)
+	return string(tuple[0].(erlangAtom)), tuple[1]
+}
+
+// formatErlangTerm renders the term as Erlang source, leaving top level strings unquoted
+func formatErlangTerm(term interface{}, topLevel bool) string {
+	switch v := term.(type) {
+	case erlangAtom:
+		return string(v)
+	case erlangTerm:
+		return string(v)
+	case string:
+		if topLevel {
+			return v
+		}
+		return fmt.Sprintf("%q", v)
+	case []interface{}:
+		return "[" + formatErlangTerms(v) + "]"
+	case erlangTuple:
+		return "{" + formatErlangTerms(v) + "}"
+	}
+	return fmt.Sprint(term)
+}
+
+func formatErlangTerms(terms []interface{}) string {
+	items := make([]string, len(terms))
+	for i, term := range terms {
+		items[i] = formatErlangTerm(term, false)
+	}
+	return strings.Join(items, ",")
+}
+
+type erlangParser struct {
+	input []rune
+	pos   int
+}
+
+func (p *erlangParser) eof() bool {
+	return p.pos >= len(p.input)
+}
+
+func (p *erlangParser) peek() rune {
+	return p.input[p.pos]
+}
+
+func (p *erlangParser) errorf(format string, args ...interface{}) error {
+	line := 1 + strings.Count(string(p.input[:p.pos]), "\n")
+	return fmt.Errorf("line %d: %s", line, fmt.Sprintf(format, args...))
+}
+
+// skipWhitespace skips spaces and % comments
+func (p *erlangParser) skipWhitespace() {
+	for !p.eof() {
+		r := p.peek()
+		if r == '%' {
+			for !p.eof() && p.peek() != '\n' {
+				p.pos++
+			}
+		} else if unicode.IsSpace(r) {
+			p.pos++
+		} else {
+			return
+		}
+	}
+}
+
+func (p *erlangParser) parseTerm() (interface{}, error) {
+	if p.skipWhitespace(); p.eof() {
+		return nil, p.errorf("unexpected end of file")
+	}
+	r := p.peek()
+	switch {
+	case r == '[':
+		p.pos++
+		items, err := p.parseSequence(']')
+		return items, err
+	case r == '{':
+		p.pos++
+		items, err := p.parseSequence('}')
+		return erlangTuple(items), err
+	case r == '#' && p.pos+1 < len(p.input) && p.input[p.pos+1] == '{':
+		p.pos += 2
+		return p.parseMap()
+	case r == '"':
+		return p.parseString()
+	case r == '\'':
+		atom, err := p.parseQuoted('\'')
+		return erlangAtom(atom), err
+	case r == '<' && p.pos+1 < len(p.input) && p.input[p.pos+1] == '<':
+		return p.parseBinary()
+	case r == '<' || (r == '#' && p.pos+1 < len(p.input) && unicode.IsLetter(p.input[p.pos+1])):
+		return p.parseOpaque()
+	case r == '-' || r == '$' || unicode.IsDigit(r):
+		return erlangTerm(p.parseLiteral()), nil
+	case unicode.IsLetter(r):
+		return erlangAtom(p.parseLiteral()), nil
+	}
+	return nil, p.errorf("unexpected character %q", r)
+}
+
+// parseItem parses a term within a list, tuple or map. Terms that can't be parsed, like fun expressions, are kept as
+// opaque text up to the next ',' or the closing rune, so a single unknown value doesn't fail the whole configuration.
+func (p *erlangParser) parseItem(closing rune) (interface{}, error) {
+	start := p.pos
+	item, err := p.parseTerm()
+	if err == nil {
+		if p.skipWhitespace(); !p.eof() && (p.peek() == ',' || p.peek() == closing) {
+			return item, nil
+		}
+	}
+	p.pos = start
+	return p.parseUnknown(closing)
+}
+
+// parseUnknown reads the text up to the next ',' or the closing rune at the same nesting depth
+func (p *erlangParser) parseUnknown(closing rune) (interface{}, error) {
+	if p.skipWhitespace(); p.eof() {
+		return nil, p.errorf("unexpected end of file")
+	}
+	start := p.pos
+	var nesting []rune
+	for !p.eof() {
+		r := p.peek()
+		switch {
+		case r == '"' || r == '\'':
+			if _, err := p.parseQuoted(r); err != nil {
+				return nil, err
+			}
+			continue
+		case r == '[':
+			nesting = append(nesting, ']')
+		case r == '{':
+			nesting = append(nesting, '}')
+		case r == '(':
+			nesting = append(nesting, ')')
+		case len(nesting) > 0 && r == nesting[len(nesting)-1]:
+			nesting = nesting[:len(nesting)-1]
+		case len(nesting) == 0 && (r == ',' || r == closing):
+			if p.pos == start {
+				return nil, p.errorf("unexpected character %q", r)
+			}
+			return erlangTerm(strings.TrimSpace(string(p.input[start:p.pos]))), nil
+		}
+		p.pos++
+	}
+	return nil, p.errorf("missing %q", closing)
+}
+
+// parseSequence parses comma separated terms up to the closing rune, used for both lists and tuples
+func (p *erlangParser) parseSequence(closing rune) ([]interface{}, error) {
+	items := []interface{}{}
+	for {
+		if p.skipWhitespace(); p.eof() {
+			return nil, p.errorf("missing %q", closing)
+		}
+		if p.peek() == closing {
+			p.pos++
+			return items, nil
+		}
+		if len(items) > 0 {
+			if p.peek() != ',' {
+				return nil, p.errorf("expected ',' or %q but found %q", closing, p.peek())
+			}
+			p.pos++
+		}
+		item, err := p.parseItem(closing)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+}
+
+// parseMap parses an Erlang map as a list of {key, value} tuples, so it's flattened like a proplist
+func (p *erlangParser) parseMap() (interface{}, error) {
+	items := []interface{}{}
+	for {
+		if p.skipWhitespace(); p.eof() {
+			return nil, p.errorf("missing '}'")
+		}
+		if p.peek() == '}' {
+			p.pos++
+			return items, nil
+		}
+		if len(items) > 0 {
+			if p.peek() != ',' {
+				return nil, p.errorf("expected ',' or '}' but found %q", p.peek())
+			}
+			p.pos++
+		}
+		key, err := p.parseTerm()
+		if err != nil {
+			return nil, err
+		}
+		if p.skipWhitespace(); p.pos+1 >= len(p.input) || string(p.input[p.pos:p.pos+2]) != "=>" {
+			return nil, p.errorf("expected '=>' in map")
+		}
+		p.pos += 2
+		value, err := p.parseItem('}')
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, erlangTuple{key, value})
+	}
+}
+
+// parseString parses a string, joining the adjacent string literals as the Erlang compiler does, e.g. "a" "b" is "ab"
+func (p *erlangParser) parseString() (interface{}, error) {
+	var sb strings.Builder
+	for {
+		value, err := p.parseQuoted('"')
+		if err != nil {
+			return nil, err
+		}
+		sb.WriteString(value)
+		if p.skipWhitespace(); p.eof() || p.peek() != '"' {
+			return sb.String(), nil
+		}
+	}
+}
+
+func (p *erlangParser) parseQuoted(quote rune) (string, error) {
+	var sb strings.Builder
+	p.pos++
+	for !p.eof() {
+		r := p.peek()
+		p.pos++
+		switch r {
+		case quote:
+			return sb.String(), nil
+		case '\\':
+			if p.eof() {
+				return "", p.errorf("unterminated escape sequence")
+			}
+			sb.WriteRune(unescapeErlang(p.peek()))
+			p.pos++
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	return "", p.errorf("unterminated %c", quote)
+}
+
+// parseBinary parses <<"text">> and <<bytes>> binaries, returning the text within them
+func (p *erlangParser) parseBinary() (interface{}, error) {
+	p.pos += 2
+	if p.skipWhitespace(); p.eof() {
+		return nil, p.errorf("unterminated binary")
+	}
+	if p.peek() == '"' {
+		value, err := p.parseQuoted('"')
+		if err != nil {
+			return nil, err
+		}
+		if p.skipWhitespace(); p.pos+1 >= len(p.input) || string(p.input[p.pos:p.pos+2]) != ">>" {
+			return nil, p.errorf("unterminated binary")
+		}
+		p.pos += 2
+		return value, nil
+	}
+	start := p.pos - 2
+	for p.pos+1 < len(p.input) && string(p.input[p.pos:p.pos+2]) != ">>" {
+		p.pos++
+	}
+	if p.pos+1 >= len(p.input) {
+		return nil, p.errorf("unterminated binary")
+	}
+	p.pos += 2
+	return erlangTerm(p.input[start:p.pos]), nil
+}
+
+// parseOpaque reads runtime values that can't be written in a configuration file but are printed in the
+// application environment, such as pids (<0.123.0>) and funs (#Fun<module.0.123>)
+func (p *erlangParser) parseOpaque() (interface{}, error) {
+	start := p.pos
+	for !p.eof() && p.peek() != '>' {
+		p.pos++
+	}
+	if p.eof() {
+		return nil, p.errorf("missing '>'")
+	}
+	p.pos++
+	return erlangTerm(p.input[start:p.pos]), nil
+}
+
+// parseLiteral reads unquoted atoms and numbers, e.g. verify_peer, rabbit@host, 16#FF, 1.5e3 or $a
+func (p *erlangParser) parseLiteral() string {
+	start := p.pos
+	if p.peek() == '$' && p.pos+1 < len(p.input) {
+		p.pos += 2
+		return string(p.input[start:p.pos])
+	}
+	p.pos++
+	for !p.eof() {
+		r := p.peek()
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '@' || r == '#' {
+			p.pos++
+		} else if r == '.' && p.pos+1 < len(p.input) && unicode.IsDigit(p.input[p.pos+1]) {
+			// a dot followed by a digit is a decimal point, otherwise it ends the configuration
+			p.pos++
+		} else if (r == '-' || r == '+') && (p.input[p.pos-1] == 'e' || p.input[p.pos-1] == 'E') && isNumber(p.input[start:p.pos]) {
+			p.pos++
+		} else {
+			break
+		}
+	}
+	return string(p.input[start:p.pos])
+}
+
+// isNumber returns true if the literal read so far is a number, possibly negative, so a sign after e is an exponent
+func isNumber(literal []rune) bool {
+	if len(literal) > 1 && literal[0] == '-' {
+		literal = literal[1:]
+	}
+	return len(literal) > 0 && unicode.IsDigit(literal[0])
+}
+
+func unescapeErlang(r rune) rune {
+	switch r {
+	case 'n':
+		return '\n'
+	case 't':
+		return '\t'
+	case 'r':
+		return '\r'
+	case 's':
+		return ' '
+	}
+	return r
+}
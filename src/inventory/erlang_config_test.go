@@ -0,0 +1,82 @@
+package inventory
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func Test_parseErlangConfigInventory(t *testing.T) {
+	file, err := os.Open(filepath.Join("testdata", "advanced.config"))
+	require.NoError(t, err)
+	defer file.Close()
+
+	config, err := parseErlangConfigInventory(file)
+	require.NoError(t, err)
+
+	expected := map[string]string{
+		"rabbit.tcp_listeners":                      `[5672,{"127.0.0.1",5673}]`,
+		"rabbit.default_user":                       "guest",
+		"rabbit.loopback_users":                     "[]",
+		"rabbit.vm_memory_high_watermark":           "0.4",
+		"rabbit.cluster_partition_handling":         "pause_minority",
+		"rabbit.ssl_options.cacertfile":             "/path/to/ca_certificate.pem",
+		"rabbit.ssl_options.verify":                 "verify_peer",
+		"rabbit.ssl_options.fail_if_no_peer_cert":   "true",
+		"rabbit.ssl_options.versions":               "[tlsv1.3,tlsv1.2]",
+		"rabbit.cluster_nodes":                      "{[rabbit@node1,rabbit@node2],disc}",
+		"rabbitmq_management.listener.port":         "15672",
+		"rabbitmq_management.listener.ssl":          "false",
+		"rabbitmq_management.cors_allow_origins":    `["*"]`,
+		"rabbitmq_auth_backend_ldap.servers":        `["ldap.example.com"]`,
+		"rabbitmq_auth_backend_ldap.dn_lookup_bind": `{"cn=admin,dc=example,dc=com","secret"}`,
+		"kernel.logger_level":                       "info",
+		"kernel.net_ticktime":                       "-1",
+	}
+	assert.Equal(t, len(expected), len(config))
+	for k, v := range expected {
+		assert.Equal(t, v, config[inventoryKey{"config", k}], k)
+	}
+}
+
+func Test_parseErlangConfigInventory_Empty(t *testing.T) {
+	config, err := parseErlangConfigInventory(strings.NewReader("%% nothing configured\n[]."))
+	assert.NoError(t, err)
+	assert.Empty(t, config)
+
+	config, err = parseErlangConfigInventory(strings.NewReader(""))
+	assert.NoError(t, err)
+	assert.Empty(t, config)
+}
+
+func Test_parseErlangConfigInventory_Errors(t *testing.T) {
+	invalid := []string{
+		`[{rabbit, [{default_user, <<"guest">>}]}`,
+		`[{rabbit, [{default_user, "guest}]}].`,
+		`{rabbit, []}.`,
+		`[{rabbit, []}]. extra`,
+	}
+	for _, config := range invalid {
+		_, err := parseErlangConfigInventory(strings.NewReader(config))
+		assert.Error(t, err, config)
+	}
+}
+
+func Test_parseErlangConfigInventory_UnknownTerms(t *testing.T) {
+	config, err := parseErlangConfigInventory(strings.NewReader(`[
+  {rabbit, [{auth_backends, fun (User) -> {ok, User} end}, {heartbeat, 60}]},
+  {kernel, [{logger_level, #{level info}}, {filter, fun logger_filters:progress/2}, {ratio, -1.5e-3}]},
+  {app, [{banner, "a" "b"}]}
+].`))
+	require.NoError(t, err)
+	assert.Equal(t, "fun (User) -> {ok, User} end", config[inventoryKey{"config", "rabbit.auth_backends"}])
+	assert.Equal(t, "60", config[inventoryKey{"config", "rabbit.heartbeat"}])
+	assert.Equal(t, "#{level info}", config[inventoryKey{"config", "kernel.logger_level"}])
+	assert.Equal(t, "fun logger_filters:progress/2", config[inventoryKey{"config", "kernel.filter"}])
+	assert.Equal(t, "-1.5e-3", config[inventoryKey{"config", "kernel.ratio"}])
+	assert.Equal(t, "ab", config[inventoryKey{"config", "app.banner"}])
+}
@@ -0,0 +1,104 @@
+package inventory
+
+import (
+	"fmt"
+
+	"github.com/newrelic/nri-rabbitmq/src/data"
+	"github.com/newrelic/nri-rabbitmq/src/state"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/data/event"
+	"github.com/newrelic/infra-integrations-sdk/v3/integration"
+	"github.com/newrelic/infra-integrations-sdk/v3/log"
+	"github.com/newrelic/infra-integrations-sdk/v3/persist"
+)
+
+const (
+	featureFlagsCategory       = "feature_flags"
+	deprecatedFeaturesCategory = "deprecated_features"
+)
+
+// CollectFeatureEvents adds events to the local node when a stable feature flag is disabled or a deprecated
+// feature starts being used, and again when that clears. The store keeps the flags and features seen in the previous run.
+// The lists are nil when their endpoint couldn't be collected, so their state is kept until the next run.
+func CollectFeatureEvents(rabbitmqIntegration *integration.Integration, store persist.Storer, nodesData []*data.NodeData, nodeName string, features *data.FeaturesData, clusterName string) {
+	if features == nil || (features.FeatureFlags == nil && features.DeprecatedFeatures == nil) {
+		return
+	}
+
+	localNode, _ := getLocalNode(rabbitmqIntegration, nodesData, nodeName, clusterName)
+	// Don't add events for the entity if we are skipping its collection
+	if localNode == nil {
+		return
+	}
+
+	if features.FeatureFlags != nil {
+		states := make(map[string]string, len(features.FeatureFlags))
+		var disabled []string
+		for _, flag := range features.FeatureFlags {
+			states[flag.Name] = flag.State
+			if flag.IsStableAndDisabled() {
+				disabled = append(disabled, flag.Name)
+			}
+		}
+		changeFeatures(localNode, store, "feature/stableDisabled", disabled, func(name string) string {
+			return fmt.Sprintf("Stable feature flag [%s] is [%s], it must be enabled before upgrading", name, states[name])
+		}, func(name string) string {
+			if flagState, ok := states[name]; ok {
+				return fmt.Sprintf("Stable feature flag [%s] is [%s]", name, flagState)
+			}
+			return fmt.Sprintf("Stable feature flag [%s] is no longer reported", name)
+		})
+	}
+
+	if features.DeprecatedFeatures != nil {
+		phases := make(map[string]string, len(features.DeprecatedFeatures))
+		inUse := make([]string, 0, len(features.DeprecatedFeatures))
+		for _, feature := range features.DeprecatedFeatures {
+			phases[feature.Name] = feature.DeprecationPhase
+			inUse = append(inUse, feature.Name)
+		}
+		changeFeatures(localNode, store, "feature/deprecatedInUse", inUse, func(name string) string {
+			return fmt.Sprintf("Deprecated feature [%s] is in use, deprecation phase [%s]", name, phases[name])
+		}, func(name string) string {
+			return fmt.Sprintf("Deprecated feature [%s] is no longer in use", name)
+		})
+	}
+}
+
+// changeFeatures adds the events described by started for the names missing from the list saved under key, and by
+// cleared for the saved names missing from current. The list is only saved once all the events are added.
+func changeFeatures(localNode *integration.Entity, store persist.Storer, key string, current []string, started, cleared func(name string) string) {
+	added, removed, _ := state.CheckMembers(store, key, current)
+	for _, name := range added {
+		if !addEvent(localNode, started(name)) {
+			return
+		}
+	}
+	for _, name := range removed {
+		if !addEvent(localNode, cleared(name)) {
+			return
+		}
+	}
+	state.RecordMembers(store, key, current)
+}
+
+func collectFeatureInventory(localNode *integration.Entity, features *data.FeaturesData) {
+	if features == nil {
+		return
+	}
+	for _, flag := range features.FeatureFlags {
+		data.SetInventoryItem(localNode, featureFlagsCategory, flag.Name, flag.State)
+	}
+	for _, feature := range features.DeprecatedFeatures {
+		data.SetInventoryItem(localNode, deprecatedFeaturesCategory, feature.Name, feature.DeprecationPhase)
+	}
+}
+
+// addEvent adds an event to the entity, returning false if it couldn't be added
+func addEvent(entity *integration.Entity, description string) bool {
+	if err := entity.AddEvent(event.New(description, "integration")); err != nil {
+		log.Error("Error adding event: %v", err)
+		return false
+	}
+	return true
+}
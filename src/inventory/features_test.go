@@ -0,0 +1,105 @@
+package inventory
+
+import (
+	"testing"
+
+	"github.com/newrelic/nri-rabbitmq/src/args"
+	"github.com/newrelic/nri-rabbitmq/src/data"
+	"github.com/newrelic/nri-rabbitmq/src/testutils"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/integration"
+	"github.com/newrelic/infra-integrations-sdk/v3/persist"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testFeatures = &data.FeaturesData{
+	FeatureFlags: []*data.FeatureFlagData{
+		{Name: "quorum_queue", State: "enabled", Stability: "required"},
+		{Name: "stream_queue", State: "enabled", Stability: "stable"},
+		{Name: "message_containers", State: "disabled", Stability: "stable"},
+		{Name: "khepri_db", State: "disabled", Stability: "experimental"},
+	},
+	DeprecatedFeatures: []*data.DeprecatedFeatureData{
+		{Name: "transient_nonexcl_queues", DeprecationPhase: "permitted_by_default"},
+	},
+}
+
+func TestCollectInventory_Features(t *testing.T) {
+	args.GlobalArgs = args.RabbitMQArguments{}
+	i := testutils.GetTestingIntegration(t)
+	nodesData := []*data.NodeData{
+		{Name: expectedNodeName},
+	}
+
+	CollectInventory(i, nodesData, expectedNodeName, testFeatures, "testClusterName")
+	require.Equal(t, 1, len(i.Entities))
+
+	expectedInventory := map[string]interface{}{
+		"config/nodeName":                              expectedNodeName,
+		"feature_flags/quorum_queue":                   "enabled",
+		"feature_flags/stream_queue":                   "enabled",
+		"feature_flags/message_containers":             "disabled",
+		"feature_flags/khepri_db":                      "disabled",
+		"deprecated_features/transient_nonexcl_queues": "permitted_by_default",
+	}
+	assert.Equal(t, len(expectedInventory), len(i.Entities[0].Inventory.Items()))
+	for k, v := range expectedInventory {
+		item, exists := i.Entities[0].Inventory.Item(k)
+		assert.True(t, exists, k)
+		assert.Equal(t, v, item["value"], k)
+	}
+}
+
+func TestCollectFeatureEvents(t *testing.T) {
+	args.GlobalArgs = args.RabbitMQArguments{}
+	store := persist.NewInMemoryStore()
+	nodesData := []*data.NodeData{
+		{Name: expectedNodeName},
+	}
+	summaries := func(i *integration.Integration) []string {
+		var summaries []string
+		for _, e := range i.Entities {
+			for _, event := range e.Events {
+				summaries = append(summaries, event.Summary)
+			}
+		}
+		return summaries
+	}
+
+	i := testutils.GetTestingIntegration(t)
+	CollectFeatureEvents(i, store, nodesData, expectedNodeName, nil, "testClusterName")
+	CollectFeatureEvents(i, store, nodesData, expectedNodeName, &data.FeaturesData{}, "testClusterName")
+	assert.Empty(t, i.Entities)
+
+	i = testutils.GetTestingIntegration(t)
+	CollectFeatureEvents(i, store, nodesData, expectedNodeName, testFeatures, "testClusterName")
+	require.Equal(t, 1, len(i.Entities))
+	assert.Equal(t, []string{
+		"Stable feature flag [message_containers] is [disabled], it must be enabled before upgrading",
+		"Deprecated feature [transient_nonexcl_queues] is in use, deprecation phase [permitted_by_default]",
+	}, summaries(i))
+
+	i = testutils.GetTestingIntegration(t)
+	CollectFeatureEvents(i, store, nodesData, expectedNodeName, testFeatures, "testClusterName")
+	assert.Empty(t, summaries(i), "no events are expected while the features are unchanged")
+
+	// the deprecated features endpoint couldn't be collected, so its state is kept
+	i = testutils.GetTestingIntegration(t)
+	CollectFeatureEvents(i, store, nodesData, expectedNodeName, &data.FeaturesData{FeatureFlags: testFeatures.FeatureFlags}, "testClusterName")
+	assert.Empty(t, summaries(i))
+
+	i = testutils.GetTestingIntegration(t)
+	CollectFeatureEvents(i, store, nodesData, expectedNodeName, &data.FeaturesData{
+		FeatureFlags: []*data.FeatureFlagData{
+			{Name: "quorum_queue", State: "enabled", Stability: "required"},
+			{Name: "message_containers", State: "enabled", Stability: "stable"},
+			{Name: "khepri_db", State: "disabled", Stability: "experimental"},
+		},
+		DeprecatedFeatures: []*data.DeprecatedFeatureData{},
+	}, "testClusterName")
+	assert.Equal(t, []string{
+		"Stable feature flag [message_containers] is [enabled]",
+		"Deprecated feature [transient_nonexcl_queues] is no longer in use",
+	}, summaries(i))
+}
@@ -3,13 +3,12 @@ package inventory
 import (
 	"bufio"
 	"bytes"
-	"errors"
 	"fmt"
 	"io"
 	"os"
-	"os/exec"
+	"path/filepath"
+	"sort"
 	"strings"
-	"unicode"
 
 	"github.com/newrelic/nri-rabbitmq/src/args"
 	"github.com/newrelic/nri-rabbitmq/src/data"
@@ -19,73 +18,80 @@ import (
 	"github.com/newrelic/infra-integrations-sdk/v3/log"
 )
 
-var (
-	execCommand = exec.Command
-	osOpen      = os.Open
-)
+// confDDir is the directory holding the configuration fragments loaded after rabbitmq.conf, available since RabbitMQ 3.7
+const confDDir = "conf.d"
+
+// advancedConfigName is the file holding the settings that can only be written as Erlang terms, next to rabbitmq.conf
+const advancedConfigName = "advanced.config"
+
+var osOpen = os.Open
 
 type inventoryKey struct {
 	category string
 	key      string
 }
 
-// CollectInventory collects the inventory items (config file values) from the apiResponses
-func CollectInventory(rabbitmqIntegration *integration.Integration, nodesData []*data.NodeData, clusterName string) {
-	if len(nodesData) == 0 {
-		log.Warn("No node data available to collect inventory")
-		return
-	}
-
-	nodeName, err := getLocalNodeName()
-	if err != nil {
-		log.Error("Error getting local node name: %s", err)
-		return
-	}
+// configValue is a configuration value along with the file it was read from
+type configValue struct {
+	value  string
+	source string
+}
 
-	nodeData, err := findNodeData(nodeName, nodesData)
-	if err != nil {
-		log.Error("Error finding node: %s", err)
+// CollectInventory collects the inventory items (config file values and feature flags) from the apiResponses
+func CollectInventory(rabbitmqIntegration *integration.Integration, nodesData []*data.NodeData, nodeName string, features *data.FeaturesData, clusterName string) {
+	localNode, nodeData := getLocalNode(rabbitmqIntegration, nodesData, nodeName, clusterName)
+	if nodeData == nil {
 		return
 	}
 
-	localNode, _, err := data.CreateEntity(rabbitmqIntegration, nodeName, consts.NodeType, "", clusterName)
-	if err != nil {
-		log.Error("Error creating local node entity: %s", err)
-	}
-
-	if config := getConfigData(nodeData); len(config) > 0 {
+	config := getConfigData(nodeData)
+	if len(config) > 0 {
 		for k, v := range config {
-			data.SetInventoryItem(localNode, k.category, k.key, v)
+			data.SetInventoryItem(localNode, k.category, k.key, v.value)
+			setInventoryField(localNode, k, "source", v.source)
 		}
 	} else {
 		data.SetInventoryItem(localNode, "config", "nodeName", nodeName)
 	}
+
+	if args.GlobalArgs.EffectiveConfig {
+		collectEffectiveConfig(localNode, nodeName, config)
+	}
+
+	collectFeatureInventory(localNode, features)
 }
 
-func getLocalNodeName() (string, error) {
-	if len(args.GlobalArgs.NodeNameOverride) > 0 {
-		return args.GlobalArgs.NodeNameOverride, nil
+// getLocalNode finds the data of the node running alongside the integration and creates its entity
+func getLocalNode(rabbitmqIntegration *integration.Integration, nodesData []*data.NodeData, nodeName, clusterName string) (*integration.Entity, *data.NodeData) {
+	if len(nodesData) == 0 {
+		log.Warn("No node data available to collect inventory")
+		return nil, nil
 	}
-	cmd := execCommand("rabbitmqctl", "eval", "node().")
-	for _, env := range os.Environ() {
-		if strings.HasPrefix(env, "HOSTNAME") {
-			continue
-		}
-		cmd.Env = append(cmd.Env, env)
+	if nodeName == "" {
+		return nil, nil
 	}
-	output, err := cmd.Output()
+
+	nodeData, err := findNodeData(nodeName, nodesData)
 	if err != nil {
-		return "", err
+		log.Error("Error finding node: %s", err)
+		return nil, nil
 	}
-	output = bytes.TrimFunc(output, trimNodeName)
-	if len(output) == 0 {
-		return "", errors.New("could not determine the local node name")
+
+	localNode, _, err := data.CreateEntity(rabbitmqIntegration, nodeName, consts.NodeType, "", clusterName)
+	if err != nil {
+		log.Error("Error creating local node entity: %s", err)
 	}
-	return string(output), nil
+	return localNode, nodeData
 }
 
-func trimNodeName(r rune) bool {
-	return unicode.IsSpace(r) || r == '\''
+// setInventoryField sets an additional field of an inventory item, such as the file it was read from
+func setInventoryField(entity *integration.Entity, key inventoryKey, field string, value interface{}) {
+	if entity == nil || value == "" {
+		return
+	}
+	if err := entity.SetInventoryItem(key.category+"/"+key.key, field, value); err != nil {
+		log.Warn("Error setting inventory [%s/%s] field [%s]: %v", key.category, key.key, field, err)
+	}
 }
 
 func findNodeData(nodeName string, nodesData []*data.NodeData) (nodeData *data.NodeData, err error) {
@@ -97,46 +103,126 @@ func findNodeData(nodeName string, nodesData []*data.NodeData) (nodeData *data.N
 	return nil, fmt.Errorf("node name [%v] not found in RabbitMQ", nodeName)
 }
 
-func getConfigData(nodeData *data.NodeData) map[inventoryKey]string {
-	configPath := getConfigPath(nodeData)
-	if len(configPath) > 0 {
-		file, err := osOpen(configPath)
-		if os.IsNotExist(err) {
-			log.Error("The specified configuration file does not exist: %v", args.GlobalArgs.ConfigPath)
-			return nil
+// getConfigData parses every configuration file of the node, recording which file each key came from
+func getConfigData(nodeData *data.NodeData) map[inventoryKey]configValue {
+	return readConfigData(nodeData, true)
+}
+
+// readConfigData parses the configuration files of the node, logging the missing ones only if reportMissing is set
+func readConfigData(nodeData *data.NodeData, reportMissing bool) map[inventoryKey]configValue {
+	var values map[inventoryKey]configValue
+	for _, configPath := range getConfigPaths(nodeData) {
+		config := parseConfigFile(configPath, reportMissing)
+		if len(config) == 0 {
+			continue
 		}
-		if err != nil {
-			log.Error("Could not open the specified configuration file: %v", err)
-			return nil
+		if values == nil {
+			values = make(map[inventoryKey]configValue)
 		}
-		defer func() {
-			if err := file.Close(); err != nil {
-				log.Error("Error closing config file [%s]: %v", configPath, err)
-			}
-		}()
+		for k, v := range config {
+			values[k] = configValue{value: v, source: configPath}
+		}
+	}
+	return values
+}
 
-		if config, err := parseConfigInventory(file); err != nil {
-			log.Error("Error parsing config file: %v", err)
-		} else {
-			return config
+func parseConfigFile(configPath string, reportMissing bool) map[inventoryKey]string {
+	file, err := osOpen(configPath)
+	if os.IsNotExist(err) {
+		if reportMissing {
+			log.Error("The specified configuration file does not exist: %v", configPath)
+		}
+		return nil
+	}
+	if err != nil {
+		log.Error("Could not open the specified configuration file: %v", err)
+		return nil
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Error("Error closing config file [%s]: %v", configPath, err)
 		}
+	}()
 
+	parse := parseConfigInventory
+	if isErlangConfig(configPath) {
+		parse = parseErlangConfigInventory
 	}
-	return nil
+	config, err := parse(file)
+	if err != nil {
+		log.Error("Error parsing config file [%s]: %v", configPath, err)
+		return nil
+	}
+	return config
 }
 
-func getConfigPath(nodeData *data.NodeData) string {
-	if len(args.GlobalArgs.ConfigPath) > 0 {
-		return args.GlobalArgs.ConfigPath
+// getConfigPaths returns the configuration files to collect in the order RabbitMQ loads them: the new style (.conf)
+// files, followed by the conf.d fragments in lexical order and the Erlang term (.config) files, as advanced.config
+// is applied on top of the rest. The advanced.config next to a ConfigPath ending in .conf is also collected.
+func getConfigPaths(nodeData *data.NodeData) []string {
+	if configPath := args.GlobalArgs.ConfigPath; len(configPath) > 0 {
+		paths := withConfDFragments([]string{configPath})
+		if strings.HasSuffix(configPath, ".conf") {
+			advancedConfig := filepath.Join(filepath.Dir(configPath), advancedConfigName)
+			if _, err := os.Stat(advancedConfig); err == nil {
+				paths = append(paths, advancedConfig)
+			}
+		}
+		return paths
 	}
+	var paths, erlangPaths []string
 	if nodeData != nil {
 		for _, config := range nodeData.ConfigFiles {
 			if strings.HasSuffix(config, ".conf") {
-				return config
+				paths = append(paths, config)
+			} else if isErlangConfig(config) {
+				erlangPaths = append(erlangPaths, config)
+			}
+		}
+	}
+	return append(withConfDFragments(paths), erlangPaths...)
+}
+
+// withConfDFragments adds the fragments in the conf.d directory next to each .conf file, moving every fragment after
+// the main configuration files and sorting them lexically, as they override the main files and each other in that order
+func withConfDFragments(paths []string) []string {
+	var mainPaths, fragments []string
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		if isConfDFragment(path) {
+			fragments = append(fragments, path)
+		} else {
+			mainPaths = append(mainPaths, path)
+		}
+		seen[path] = true
+	}
+	for _, path := range mainPaths {
+		if !strings.HasSuffix(path, ".conf") {
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(filepath.Dir(path), confDDir, "*.conf"))
+		if err != nil {
+			log.Warn("Error listing the conf.d fragments next to [%s]: %v", path, err)
+			continue
+		}
+		for _, match := range matches {
+			if !seen[match] {
+				fragments = append(fragments, match)
+				seen[match] = true
 			}
 		}
 	}
-	return ""
+	sort.Strings(fragments)
+	return append(mainPaths, fragments...)
+}
+
+func isConfDFragment(configPath string) bool {
+	return filepath.Base(filepath.Dir(configPath)) == confDDir
+}
+
+// isErlangConfig returns true for advanced.config and the legacy rabbitmq.config files
+func isErlangConfig(configPath string) bool {
+	return strings.HasSuffix(configPath, ".config")
 }
 
 func parseConfigInventory(reader io.Reader) (map[inventoryKey]string, error) {
@@ -164,3 +250,27 @@ func parseConfigInventory(reader io.Reader) (map[inventoryKey]string, error) {
 	}
 	return values, scanner.Err()
 }
+
+// defaultPartitionHandling is the cluster_partition_handling strategy used by RabbitMQ when it's not configured
+const defaultPartitionHandling = "ignore"
+
+// GetPartitionHandling returns the cluster_partition_handling strategy configured for the local node, or an empty
+// string if its configuration can't be read. The configuration files are missing when the integration doesn't run on
+// the node's host, which is not reported.
+func GetPartitionHandling(nodesData []*data.NodeData, nodeName string) string {
+	nodeData, err := findNodeData(nodeName, nodesData)
+	if err != nil {
+		return ""
+	}
+	config := readConfigData(nodeData, false)
+	if config == nil {
+		return ""
+	}
+	// advanced.config takes precedence over rabbitmq.conf
+	for _, key := range []string{"rabbit.cluster_partition_handling", "cluster_partition_handling"} {
+		if v, ok := config[inventoryKey{"config", key}]; ok {
+			return v.value
+		}
+	}
+	return defaultPartitionHandling
+}
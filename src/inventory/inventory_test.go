@@ -1,11 +1,10 @@
 package inventory
 
 import (
+	"bytes"
 	"errors"
-	"fmt"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"testing"
 
@@ -22,11 +21,7 @@ func errorOsOpen(name string) (*os.File, error) {
 	return nil, errors.New("an error other than file not found")
 }
 
-const (
-	expectedNodeCmdOutput = `'node1'
-`
-	expectedNodeName = "node1"
-)
+const expectedNodeName = "node1"
 
 var testConfigPath = filepath.Join("testdata", "sample.conf")
 
@@ -35,28 +30,18 @@ func TestCollectInventory(t *testing.T) {
 	args.GlobalArgs = args.RabbitMQArguments{}
 
 	var nodesData []*data.NodeData
-	CollectInventory(i, nodesData, "testClusterName")
+	CollectInventory(i, nodesData, expectedNodeName, nil, "testClusterName")
 	assert.Empty(t, i.Entities, "CollectInventory shouldn't create anything with empty NodeData")
 
-	prevExec := execCommand
-	execCommand = fakeExecCommand
-	os.Setenv("GO_WANT_HELPER_PROCESS", "1")
-	defer func() {
-		execCommand = prevExec
-		os.Unsetenv("GO_WANT_HELPER_PROCESS")
-	}()
-
 	nodesData = []*data.NodeData{
 		{
 			Name: "node2",
 		},
 	}
-	os.Setenv("GET_NODE_NAME_ERROR", "1")
-	CollectInventory(i, nodesData, "testClusterName")
-	assert.Empty(t, i.Entities, "CollectInventory shouldn't create anything with error getting node name")
-	os.Unsetenv("GET_NODE_NAME_ERROR")
+	CollectInventory(i, nodesData, "", nil, "testClusterName")
+	assert.Empty(t, i.Entities, "CollectInventory shouldn't create anything without the local node name")
 
-	CollectInventory(i, nodesData, "testClustreName")
+	CollectInventory(i, nodesData, expectedNodeName, nil, "testClustreName")
 	assert.Empty(t, i.Entities, "CollectInventory shouldn't create anything with mismatched nodeData")
 
 	nodesData = []*data.NodeData{
@@ -68,11 +53,11 @@ func TestCollectInventory(t *testing.T) {
 
 	prevOsOpen := osOpen
 	osOpen = errorOsOpen
-	CollectInventory(i, nodesData, "testClusterName")
+	CollectInventory(i, nodesData, expectedNodeName, nil, "testClusterName")
 	assert.NotEmpty(t, i.Entities, "CollectInventory should create nodeName when config file fails to open")
 	osOpen = prevOsOpen
 
-	CollectInventory(i, nodesData, "testClusterName")
+	CollectInventory(i, nodesData, expectedNodeName, nil, "testClusterName")
 	assert.Equal(t, 1, len(i.Entities), "CollectInventory should create one Entity")
 	actual, _ := i.Entities[0].Inventory.MarshalJSON()
 
@@ -89,49 +74,15 @@ func TestCollectInventory(t *testing.T) {
 }
 
 func TestCollectInventory_Errors(t *testing.T) {
-	args.GlobalArgs = args.RabbitMQArguments{
-		NodeNameOverride: "node1",
-	}
+	args.GlobalArgs = args.RabbitMQArguments{}
 	i := testutils.GetTestingIntegration(t)
 
 	nodesData := []*data.NodeData{
 		{Name: "node2"},
 	}
 
-	CollectInventory(i, nodesData, "testClusterName")
-}
-
-func Test_getLocalNodeName(t *testing.T) {
-	args.GlobalArgs = args.RabbitMQArguments{
-		NodeNameOverride: expectedNodeName,
-	}
-
-	nodeName, err := getLocalNodeName()
-	assert.NoError(t, err)
-	assert.Equal(t, expectedNodeName, nodeName)
-	args.GlobalArgs.NodeNameOverride = ""
-
-	prevExec := execCommand
-	execCommand = fakeExecCommand
-	os.Setenv("GO_WANT_HELPER_PROCESS", "1")
-	defer func() {
-		execCommand = prevExec
-		os.Unsetenv("GO_WANT_HELPER_PROCESS")
-	}()
-
-	os.Setenv("GET_NODE_NAME_ERROR", "1")
-	_, err = getLocalNodeName()
-	assert.Error(t, err)
-	os.Unsetenv("GET_NODE_NAME_ERROR")
-
-	os.Setenv("GET_NODE_NAME_EMPTY", "1")
-	_, err = getLocalNodeName()
-	assert.EqualError(t, err, "could not determine the local node name")
-	os.Unsetenv("GET_NODE_NAME_EMPTY")
-
-	nodeName, err = getLocalNodeName()
-	assert.NoError(t, err)
-	assert.Equal(t, expectedNodeName, nodeName)
+	CollectInventory(i, nodesData, "node1", nil, "testClusterName")
+	assert.Empty(t, i.Entities)
 }
 
 func Test_findNodeData(t *testing.T) {
@@ -188,16 +139,20 @@ func Test_getConfigData(t *testing.T) {
 	require.NotEmpty(t, config)
 }
 
-func Test_getConfigPath(t *testing.T) {
+func Test_getConfigPaths(t *testing.T) {
 	args.GlobalArgs = args.RabbitMQArguments{
 		ConfigPath: testConfigPath,
 	}
 
-	actual := getConfigPath(nil)
-	assert.Equal(t, testConfigPath, actual)
+	actual := getConfigPaths(nil)
+	assert.Equal(t, []string{testConfigPath, filepath.Join("testdata", "advanced.config")}, actual)
+
+	args.GlobalArgs.ConfigPath = filepath.Join("testdata", "advanced.config")
+	actual = getConfigPaths(nil)
+	assert.Equal(t, []string{filepath.Join("testdata", "advanced.config")}, actual)
 	args.GlobalArgs.ConfigPath = ""
 
-	actual = getConfigPath(nil)
+	actual = getConfigPaths(nil)
 	assert.Empty(t, actual)
 
 	nodeData := new(data.NodeData)
@@ -207,63 +162,96 @@ func Test_getConfigPath(t *testing.T) {
 			"/etc/rabbitmq/advanced.config"
 		]
 	}`, nodeData)
-	actual = getConfigPath(nodeData)
-	assert.Empty(t, actual)
+	actual = getConfigPaths(nodeData)
+	assert.Equal(t, []string{"/etc/rabbitmq/rabbitmq.config", "/etc/rabbitmq/advanced.config"}, actual)
 
 	testutils.ReadStructFromJSONString(t, `{
 		"config_files": [
+			"/etc/rabbitmq/advanced.config",
 			"/etc/rabbitmq/rabbitmq.conf",
-			"/etc/rabbitmq/advanced.config"
+			"/etc/rabbitmq/enabled_plugins"
 		]
 	}`, nodeData)
-	actual = getConfigPath(nodeData)
-	assert.Equal(t, "/etc/rabbitmq/rabbitmq.conf", actual)
+	actual = getConfigPaths(nodeData)
+	assert.Equal(t, []string{"/etc/rabbitmq/rabbitmq.conf", "/etc/rabbitmq/advanced.config"}, actual)
 }
 
-func fakeExecCommand(command string, args ...string) (cmd *exec.Cmd) {
-	cs := []string{"-test.run=TestHelperProcess", "--", command}
-	cs = append(cs, args...)
-	cmd = exec.Command(os.Args[0], cs...)
-	return cmd
-}
-
-// TestHelperProcess isn't a real test. It's used as a helper process.
-func TestHelperProcess(*testing.T) {
-	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
-		return
+func TestCollectInventory_RedactsLDAPBind(t *testing.T) {
+	i := testutils.GetTestingIntegration(t)
+	args.GlobalArgs = args.RabbitMQArguments{
+		EffectiveConfig:     true,
+		EffectiveConfigPath: testEffectiveConfigPath,
+	}
+	nodesData := []*data.NodeData{
+		{Name: expectedNodeName, ConfigFiles: []string{filepath.Join("testdata", "advanced.config")}},
 	}
-	defer os.Exit(0)
 
-	args := os.Args
-	for len(args) > 0 {
-		if args[0] == "--" {
-			args = args[1:]
-			break
+	CollectInventory(i, nodesData, expectedNodeName, nil, "testClusterName")
+	require.Equal(t, 1, len(i.Entities))
+	for _, key := range []string{"config/rabbitmq_auth_backend_ldap.dn_lookup_bind", "effective_config/rabbitmq_auth_backend_ldap.dn_lookup_bind"} {
+		item, ok := i.Entities[0].Inventory.Item(key)
+		require.True(t, ok, key)
+		assert.Equal(t, data.RedactedValue, item["value"], key)
+		if fileValue, ok := item["file_value"]; ok {
+			assert.Equal(t, data.RedactedValue, fileValue, key)
 		}
-		args = args[1:]
 	}
-	if len(args) == 0 {
-		fmt.Fprintf(os.Stderr, "No command\n")
-		os.Exit(2)
+	item, ok := i.Entities[0].Inventory.Item("config/rabbitmq_auth_backend_ldap.servers")
+	require.True(t, ok)
+	assert.NotEqual(t, data.RedactedValue, item["value"])
+}
+
+func Test_getConfigData_Merged(t *testing.T) {
+	args.GlobalArgs = args.RabbitMQArguments{}
+	advancedConfigPath := filepath.Join("testdata", "advanced.config")
+	nodeData := &data.NodeData{
+		ConfigFiles: []string{testConfigPath, advancedConfigPath},
 	}
 
-	cmd, args := args[0], args[1:]
-	switch cmd {
-	case "rabbitmqctl":
-		fakeRabbitmqctl(args)
+	config := getConfigData(nodeData)
+	assert.Equal(t, configValue{value: "value1", source: testConfigPath}, config[inventoryKey{"config", "name1"}])
+	assert.Equal(t, configValue{value: "verify_peer", source: advancedConfigPath}, config[inventoryKey{"config", "rabbit.ssl_options.verify"}])
+}
+
+func Test_getConfigPaths_ConfD(t *testing.T) {
+	args.GlobalArgs = args.RabbitMQArguments{}
+	confDir := filepath.Join("testdata", "confd")
+	mainConfig := filepath.Join(confDir, "rabbitmq.conf")
+	defaults := filepath.Join(confDir, confDDir, "10-defaults.conf")
+	overrides := filepath.Join(confDir, confDDir, "20-overrides.conf")
+
+	nodeData := &data.NodeData{
+		ConfigFiles: []string{"/etc/rabbitmq/advanced.config", overrides, mainConfig},
 	}
+	assert.Equal(t, []string{mainConfig, defaults, overrides, "/etc/rabbitmq/advanced.config"}, getConfigPaths(nodeData))
+
+	args.GlobalArgs.ConfigPath = mainConfig
+	advancedConfig := filepath.Join(confDir, "advanced.config")
+	assert.Equal(t, []string{mainConfig, defaults, overrides, advancedConfig}, getConfigPaths(nil), "advanced.config is merged with CONFIG_PATH")
+
+	config := getConfigData(nil)
+	assert.Equal(t, configValue{value: "autoheal", source: advancedConfig}, config[inventoryKey{"config", "rabbit.cluster_partition_handling"}])
+	assert.Equal(t, configValue{value: "5672", source: mainConfig}, config[inventoryKey{"config", "listeners.tcp.default"}])
+	assert.Equal(t, configValue{value: "main", source: defaults}, config[inventoryKey{"config", "default_vhost"}])
+	assert.Equal(t, configValue{value: "0.5", source: overrides}, config[inventoryKey{"config", "vm_memory_high_watermark.relative"}])
 }
 
-func fakeRabbitmqctl(args []string) {
-	if len(args) == 2 && args[0] == "eval" && args[1] == "node()." {
-		if os.Getenv("GET_NODE_NAME_ERROR") == "1" {
-			os.Exit(2)
-		}
-		if os.Getenv("GET_NODE_NAME_EMPTY") == "1" {
-			fmt.Fprintf(os.Stdout, "")
-		} else {
-			// nolint
-			fmt.Fprintf(os.Stdout, expectedNodeCmdOutput)
-		}
+func TestGetPartitionHandling(t *testing.T) {
+	args.GlobalArgs = args.RabbitMQArguments{}
+	nodesData := []*data.NodeData{
+		{Name: expectedNodeName, ConfigFiles: []string{testConfigPath}},
 	}
+	assert.Equal(t, defaultPartitionHandling, GetPartitionHandling(nodesData, expectedNodeName))
+
+	nodesData[0].ConfigFiles = append(nodesData[0].ConfigFiles, filepath.Join("testdata", "advanced.config"))
+	assert.Equal(t, "pause_minority", GetPartitionHandling(nodesData, expectedNodeName))
+
+	assert.Equal(t, "", GetPartitionHandling(nodesData, "unknown"))
+
+	var logs bytes.Buffer
+	log.SetOutput(&logs)
+	defer log.SetOutput(os.Stderr)
+	nodesData[0].ConfigFiles = []string{filepath.Join("testdata", "does-not-exist.conf")}
+	assert.Equal(t, "", GetPartitionHandling(nodesData, expectedNodeName))
+	assert.Empty(t, logs.String(), "missing files are expected when the integration is not on the node's host")
 }
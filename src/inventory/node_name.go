@@ -0,0 +1,267 @@
+package inventory
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"net"
+	"os"
+	"os/exec"
+	"strings"
+	"unicode"
+
+	"github.com/newrelic/nri-rabbitmq/src/args"
+	"github.com/newrelic/nri-rabbitmq/src/data"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/log"
+)
+
+const defaultRabbitmqEnvConfPath = "/etc/rabbitmq/rabbitmq-env.conf"
+
+var (
+	execCommand    = exec.Command
+	osHostname     = os.Hostname
+	lookupCNAME    = net.LookupCNAME
+	lookupHost     = net.LookupHost
+	interfaceAddrs = net.InterfaceAddrs
+)
+
+// nodeNameSource is a strategy used to discover the local node name, returning an empty string when it can't
+type nodeNameSource struct {
+	name     string
+	discover func(nodesData []*data.NodeData, overview *data.OverviewData) string
+}
+
+// nodeNameSources are tried in order, the first one returning a node known by the Management API wins.
+// rabbitmqctl is left to the last as it requires the Erlang cookie to be readable by the integration.
+var nodeNameSources = []nodeNameSource{
+	{"hostname", nodeNameFromHostname},
+	{"RABBITMQ_NODENAME", nodeNameFromEnv},
+	{"rabbitmq-env.conf", nodeNameFromEnvConf},
+	{"overview", nodeNameFromOverview},
+}
+
+// GetLocalNodeName returns the name of the RabbitMQ node running alongside the integration. NodeNameOverride is used if
+// set, otherwise the name is discovered from the host names, the RabbitMQ environment, the Management API and finally rabbitmqctl.
+func GetLocalNodeName(nodesData []*data.NodeData, overview *data.OverviewData) (string, error) {
+	if len(args.GlobalArgs.NodeNameOverride) > 0 {
+		return args.GlobalArgs.NodeNameOverride, nil
+	}
+	for _, source := range nodeNameSources {
+		nodeName := source.discover(nodesData, overview)
+		if nodeName == "" {
+			continue
+		}
+		if _, err := findNodeData(nodeName, nodesData); err != nil {
+			log.Debug("Ignoring local node name [%s] from %s: %v", nodeName, source.name, err)
+			continue
+		}
+		log.Debug("Local node name [%s] discovered from %s", nodeName, source.name)
+		return nodeName, nil
+	}
+	return getNodeNameFromRabbitmqctl()
+}
+
+// nodeNameFromHostname returns the node whose host part matches the local host name or FQDN, if only one does. When
+// no node host matches in full, the first label of the node hosts is compared, so rabbit@host.example.com or
+// rabbit@pod.svc.ns match the short host name even without DNS.
+func nodeNameFromHostname(nodesData []*data.NodeData, _ *data.OverviewData) string {
+	hostnames := getLocalHostnames()
+	found, ambiguous := findNodeByHost(nodesData, hostnames, func(nodeHost string) string {
+		return nodeHost
+	})
+	if found != "" || ambiguous {
+		return found
+	}
+	found, _ = findNodeByHost(nodesData, hostnames, func(nodeHost string) string {
+		if dot := strings.IndexByte(nodeHost, '.'); dot > 0 {
+			return nodeHost[:dot]
+		}
+		return nodeHost
+	})
+	return found
+}
+
+// findNodeByHost returns the only node whose host, as returned by hostOf, is one of the hostnames. It returns true
+// when several nodes match.
+func findNodeByHost(nodesData []*data.NodeData, hostnames []string, hostOf func(nodeHost string) string) (string, bool) {
+	var found string
+	for _, node := range nodesData {
+		at := strings.LastIndexByte(node.Name, '@')
+		if at < 0 {
+			continue
+		}
+		nodeHost := hostOf(strings.ToLower(node.Name[at+1:]))
+		for _, hostname := range hostnames {
+			if nodeHost == hostname {
+				if found != "" {
+					log.Debug("Multiple nodes are running on host [%s], the host name can't identify the local node", hostname)
+					return "", true
+				}
+				found = node.Name
+				break
+			}
+		}
+	}
+	return found, false
+}
+
+// getLocalHostnames returns the short and fully qualified names of the local host
+func getLocalHostnames() []string {
+	hostname, err := osHostname()
+	if err != nil || hostname == "" {
+		return nil
+	}
+	hostname = strings.ToLower(hostname)
+	hostnames := []string{hostname}
+	if fqdn, err := lookupCNAME(hostname); err == nil {
+		hostnames = appendHostname(hostnames, strings.ToLower(strings.TrimSuffix(fqdn, ".")))
+	}
+	for _, h := range hostnames {
+		if dot := strings.IndexByte(h, '.'); dot > 0 {
+			hostnames = appendHostname(hostnames, h[:dot])
+		}
+	}
+	return hostnames
+}
+
+func appendHostname(hostnames []string, hostname string) []string {
+	if hostname == "" {
+		return hostnames
+	}
+	for _, h := range hostnames {
+		if h == hostname {
+			return hostnames
+		}
+	}
+	return append(hostnames, hostname)
+}
+
+func nodeNameFromEnv(_ []*data.NodeData, _ *data.OverviewData) string {
+	return qualifyNodeName(os.Getenv("RABBITMQ_NODENAME"))
+}
+
+// nodeNameFromEnvConf reads NODENAME from rabbitmq-env.conf, which can be relocated with RABBITMQ_CONF_ENV_FILE
+func nodeNameFromEnvConf(_ []*data.NodeData, _ *data.OverviewData) string {
+	path := os.Getenv("RABBITMQ_CONF_ENV_FILE")
+	if path == "" {
+		path = defaultRabbitmqEnvConfPath
+	}
+	file, err := osOpen(path)
+	if err != nil {
+		return ""
+	}
+	defer func() {
+		if err := file.Close(); err != nil {
+			log.Error("Error closing file [%s]: %v", path, err)
+		}
+	}()
+
+	var nodeName string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		line = strings.TrimPrefix(line, "export ")
+		key, value, found := strings.Cut(line, "=")
+		if !found {
+			continue
+		}
+		if key = strings.TrimSpace(key); key == "NODENAME" || key == "RABBITMQ_NODENAME" {
+			nodeName = strings.Trim(strings.TrimSpace(value), `"'`)
+		}
+	}
+	return qualifyNodeName(nodeName)
+}
+
+// nodeNameFromOverview returns the node serving the Management API the integration is connected to. It's only the local
+// node when HOSTNAME is a local address, a remote host or a load balancer could serve the API from any node.
+func nodeNameFromOverview(_ []*data.NodeData, overview *data.OverviewData) string {
+	if overview == nil {
+		return ""
+	}
+	if !isLocalHost(args.GlobalArgs.Hostname) {
+		log.Debug("Ignoring the overview node as [%s] is not a local address", args.GlobalArgs.Hostname)
+		return ""
+	}
+	return overview.Node
+}
+
+// isLocalHost returns true if all the addresses of host are loopback addresses or belong to the local interfaces
+func isLocalHost(host string) bool {
+	if host == "" {
+		return false
+	}
+	addresses := []string{host}
+	if net.ParseIP(host) == nil {
+		var err error
+		if addresses, err = lookupHost(host); err != nil || len(addresses) == 0 {
+			return false
+		}
+	}
+	localAddrs, err := interfaceAddrs()
+	if err != nil {
+		return false
+	}
+	for _, address := range addresses {
+		ip := net.ParseIP(address)
+		if ip == nil {
+			return false
+		}
+		if !ip.IsLoopback() && !hasAddress(localAddrs, ip) {
+			return false
+		}
+	}
+	return true
+}
+
+func hasAddress(addrs []net.Addr, ip net.IP) bool {
+	for _, addr := range addrs {
+		if ipNet, ok := addr.(*net.IPNet); ok && ipNet.IP.Equal(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// qualifyNodeName appends the short host name to node names without one, as RabbitMQ does
+func qualifyNodeName(nodeName string) string {
+	if nodeName == "" || strings.Contains(nodeName, "@") {
+		return nodeName
+	}
+	hostname, err := osHostname()
+	if err != nil {
+		return ""
+	}
+	if dot := strings.IndexByte(hostname, '.'); dot > 0 {
+		hostname = hostname[:dot]
+	}
+	return nodeName + "@" + hostname
+}
+
+func getNodeNameFromRabbitmqctl() (string, error) {
+	output, err := rabbitmqCommand("rabbitmqctl", "eval", "node().").Output()
+	if err != nil {
+		return "", err
+	}
+	output = bytes.TrimFunc(output, trimNodeName)
+	if len(output) == 0 {
+		return "", errors.New("could not determine the local node name")
+	}
+	return string(output), nil
+}
+
+// rabbitmqCommand creates a RabbitMQ CLI command, leaving HOSTNAME out of its environment as it would change the target node
+func rabbitmqCommand(name string, arg ...string) *exec.Cmd {
+	cmd := execCommand(name, arg...)
+	for _, env := range os.Environ() {
+		if strings.HasPrefix(env, "HOSTNAME") {
+			continue
+		}
+		cmd.Env = append(cmd.Env, env)
+	}
+	return cmd
+}
+
+func trimNodeName(r rune) bool {
+	return unicode.IsSpace(r) || r == '\''
+}
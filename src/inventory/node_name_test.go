@@ -0,0 +1,274 @@
+package inventory
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	"github.com/newrelic/nri-rabbitmq/src/args"
+	"github.com/newrelic/nri-rabbitmq/src/data"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const expectedNodeCmdOutput = `'node1'
+`
+
+var testNodesData = []*data.NodeData{
+	{Name: "rabbit@host1"},
+	{Name: "rabbit@host2.example.com"},
+	{Name: "rabbit-a@host3"},
+	{Name: "rabbit-b@host3"},
+}
+
+func fakeHostname(hostname string) func() {
+	prevHostname, prevLookup := osHostname, lookupCNAME
+	osHostname = func() (string, error) {
+		return hostname, nil
+	}
+	lookupCNAME = func(host string) (string, error) {
+		if host == "host2" {
+			return "host2.example.com.", nil
+		}
+		return "", errors.New("no such host")
+	}
+	return func() {
+		osHostname, lookupCNAME = prevHostname, prevLookup
+	}
+}
+
+func TestGetLocalNodeName_Override(t *testing.T) {
+	args.GlobalArgs = args.RabbitMQArguments{
+		NodeNameOverride: expectedNodeName,
+	}
+	defer func() {
+		args.GlobalArgs.NodeNameOverride = ""
+	}()
+
+	nodeName, err := GetLocalNodeName(testNodesData, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, expectedNodeName, nodeName)
+}
+
+func fakeHostAddresses(addresses map[string][]string, localAddrs ...string) func() {
+	prevLookup, prevAddrs := lookupHost, interfaceAddrs
+	lookupHost = func(host string) ([]string, error) {
+		if hostAddresses, ok := addresses[host]; ok {
+			return hostAddresses, nil
+		}
+		return nil, errors.New("no such host")
+	}
+	interfaceAddrs = func() ([]net.Addr, error) {
+		var addrs []net.Addr
+		for _, addr := range localAddrs {
+			addrs = append(addrs, &net.IPNet{IP: net.ParseIP(addr), Mask: net.CIDRMask(24, 32)})
+		}
+		return addrs, nil
+	}
+	return func() {
+		lookupHost, interfaceAddrs = prevLookup, prevAddrs
+	}
+}
+
+func TestGetLocalNodeName_Chain(t *testing.T) {
+	args.GlobalArgs = args.RabbitMQArguments{Hostname: "localhost"}
+	defer fakeHostAddresses(map[string][]string{"localhost": {"127.0.0.1", "::1"}})()
+	os.Setenv("RABBITMQ_CONF_ENV_FILE", filepath.Join("testdata", "not-found.conf"))
+	defer os.Unsetenv("RABBITMQ_CONF_ENV_FILE")
+
+	defer fakeHostname("HOST1")()
+	nodeName, err := GetLocalNodeName(testNodesData, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "rabbit@host1", nodeName, "should match the short host name")
+
+	fakeHostname("host2")
+	nodeName, err = GetLocalNodeName(testNodesData, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "rabbit@host2.example.com", nodeName, "should match the FQDN")
+
+	fakeHostname("host3")
+	os.Setenv("RABBITMQ_NODENAME", "rabbit-b")
+	nodeName, err = GetLocalNodeName(testNodesData, nil)
+	os.Unsetenv("RABBITMQ_NODENAME")
+	assert.NoError(t, err)
+	assert.Equal(t, "rabbit-b@host3", nodeName, "should use RABBITMQ_NODENAME when the host name is ambiguous")
+
+	os.Setenv("RABBITMQ_CONF_ENV_FILE", filepath.Join("testdata", "rabbitmq-env.conf"))
+	nodeName, err = GetLocalNodeName(testNodesData, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "rabbit-a@host3", nodeName, "should use NODENAME from rabbitmq-env.conf")
+
+	fakeHostname("host4")
+	nodeName, err = GetLocalNodeName(testNodesData, &data.OverviewData{Node: "rabbit@host2.example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, "rabbit@host2.example.com", nodeName, "should use the node from the overview")
+
+	prevExec := execCommand
+	execCommand = fakeExecCommand
+	os.Setenv("GO_WANT_HELPER_PROCESS", "1")
+	defer func() {
+		execCommand = prevExec
+		os.Unsetenv("GO_WANT_HELPER_PROCESS")
+	}()
+
+	nodeName, err = GetLocalNodeName(testNodesData, &data.OverviewData{Node: "rabbit@unknown"})
+	assert.NoError(t, err)
+	assert.Equal(t, expectedNodeName, nodeName, "should fall back to rabbitmqctl")
+}
+
+func Test_nodeNameFromHostname_FirstLabel(t *testing.T) {
+	nodesData := []*data.NodeData{
+		{Name: "rabbit@pod-0.rabbitmq-nodes.messaging"},
+		{Name: "rabbit@pod-1.rabbitmq-nodes.messaging"},
+		{Name: "rabbit@host1.example.com"},
+		{Name: "rabbit@host1.other.com"},
+	}
+
+	defer fakeHostname("pod-1")()
+	assert.Equal(t, "rabbit@pod-1.rabbitmq-nodes.messaging", nodeNameFromHostname(nodesData, nil))
+
+	fakeHostname("host1")
+	assert.Equal(t, "", nodeNameFromHostname(nodesData, nil), "the first label matches several nodes")
+
+	fakeHostname("host1.example.com")
+	assert.Equal(t, "rabbit@host1.example.com", nodeNameFromHostname(nodesData, nil), "a full match is preferred")
+}
+
+func TestGetLocalNodeName_RemoteOverview(t *testing.T) {
+	args.GlobalArgs = args.RabbitMQArguments{Hostname: "rabbitmq.example.com"}
+	defer fakeHostAddresses(map[string][]string{"rabbitmq.example.com": {"10.0.0.5"}}, "10.0.0.2")()
+	os.Setenv("RABBITMQ_CONF_ENV_FILE", filepath.Join("testdata", "not-found.conf"))
+	defer os.Unsetenv("RABBITMQ_CONF_ENV_FILE")
+	defer fakeHostname("host4")()
+
+	prevExec := execCommand
+	execCommand = fakeExecCommand
+	os.Setenv("GO_WANT_HELPER_PROCESS", "1")
+	defer func() {
+		execCommand = prevExec
+		os.Unsetenv("GO_WANT_HELPER_PROCESS")
+	}()
+
+	nodeName, err := GetLocalNodeName(testNodesData, &data.OverviewData{Node: "rabbit@host2.example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, expectedNodeName, nodeName, "the overview node of a remote host must not be used")
+
+	args.GlobalArgs.Hostname = "10.0.0.2"
+	nodeName, err = GetLocalNodeName(testNodesData, &data.OverviewData{Node: "rabbit@host2.example.com"})
+	assert.NoError(t, err)
+	assert.Equal(t, "rabbit@host2.example.com", nodeName, "should use the overview node of a local interface address")
+}
+
+func Test_isLocalHost(t *testing.T) {
+	defer fakeHostAddresses(map[string][]string{
+		"localhost":   {"127.0.0.1", "::1"},
+		"rabbit-lb":   {"10.0.0.5"},
+		"rabbit-host": {"10.0.0.2"},
+		"mixed":       {"10.0.0.2", "10.0.0.5"},
+	}, "10.0.0.2")()
+
+	assert.True(t, isLocalHost("localhost"))
+	assert.True(t, isLocalHost("127.0.0.1"))
+	assert.True(t, isLocalHost("::1"))
+	assert.True(t, isLocalHost("rabbit-host"))
+	assert.True(t, isLocalHost("10.0.0.2"))
+	assert.False(t, isLocalHost("rabbit-lb"))
+	assert.False(t, isLocalHost("10.0.0.5"))
+	assert.False(t, isLocalHost("mixed"))
+	assert.False(t, isLocalHost("unknown"))
+	assert.False(t, isLocalHost(""))
+}
+
+func Test_getNodeNameFromRabbitmqctl(t *testing.T) {
+	prevExec := execCommand
+	execCommand = fakeExecCommand
+	os.Setenv("GO_WANT_HELPER_PROCESS", "1")
+	defer func() {
+		execCommand = prevExec
+		os.Unsetenv("GO_WANT_HELPER_PROCESS")
+	}()
+
+	os.Setenv("GET_NODE_NAME_ERROR", "1")
+	_, err := getNodeNameFromRabbitmqctl()
+	assert.Error(t, err)
+	os.Unsetenv("GET_NODE_NAME_ERROR")
+
+	os.Setenv("GET_NODE_NAME_EMPTY", "1")
+	_, err = getNodeNameFromRabbitmqctl()
+	assert.EqualError(t, err, "could not determine the local node name")
+	os.Unsetenv("GET_NODE_NAME_EMPTY")
+
+	nodeName, err := getNodeNameFromRabbitmqctl()
+	assert.NoError(t, err)
+	assert.Equal(t, expectedNodeName, nodeName)
+}
+
+func Test_qualifyNodeName(t *testing.T) {
+	defer fakeHostname("host1.example.com")()
+	assert.Equal(t, "", qualifyNodeName(""))
+	assert.Equal(t, "rabbit@other", qualifyNodeName("rabbit@other"))
+	assert.Equal(t, "rabbit@host1", qualifyNodeName("rabbit"))
+}
+
+func fakeExecCommand(command string, args ...string) (cmd *exec.Cmd) {
+	cs := []string{"-test.run=TestHelperProcess", "--", command}
+	cs = append(cs, args...)
+	cmd = exec.Command(os.Args[0], cs...)
+	return cmd
+}
+
+// TestHelperProcess isn't a real test. It's used as a helper process.
+func TestHelperProcess(*testing.T) {
+	if os.Getenv("GO_WANT_HELPER_PROCESS") != "1" {
+		return
+	}
+	defer os.Exit(0)
+
+	args := os.Args
+	for len(args) > 0 {
+		if args[0] == "--" {
+			args = args[1:]
+			break
+		}
+		args = args[1:]
+	}
+	if len(args) == 0 {
+		fmt.Fprintf(os.Stderr, "No command\n")
+		os.Exit(2)
+	}
+
+	cmd, args := args[0], args[1:]
+	switch cmd {
+	case "rabbitmqctl":
+		fakeRabbitmqctl(args)
+	case "rabbitmq-diagnostics":
+		fakeRabbitmqDiagnostics(args)
+	}
+}
+
+func fakeRabbitmqctl(args []string) {
+	if len(args) == 2 && args[0] == "eval" && args[1] == "node()." {
+		if os.Getenv("GET_NODE_NAME_ERROR") == "1" {
+			os.Exit(2)
+		}
+		if os.Getenv("GET_NODE_NAME_EMPTY") == "1" {
+			fmt.Fprintf(os.Stdout, "")
+		} else {
+			// nolint
+			fmt.Fprintf(os.Stdout, expectedNodeCmdOutput)
+		}
+	}
+}
+
+func fakeRabbitmqDiagnostics(args []string) {
+	if len(args) == 4 && args[3] == "environment" && args[2] == expectedNodeName {
+		environment, _ := os.ReadFile(filepath.Join("testdata", "environment.txt"))
+		fmt.Fprint(os.Stdout, string(environment))
+		return
+	}
+	os.Exit(2)
+}
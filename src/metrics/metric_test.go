@@ -5,12 +5,14 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/newrelic/nri-rabbitmq/src/args"
 	"github.com/newrelic/nri-rabbitmq/src/data"
 	"github.com/newrelic/nri-rabbitmq/src/data/consts"
 	"github.com/newrelic/nri-rabbitmq/src/testutils"
 
+	"github.com/newrelic/infra-integrations-sdk/v3/data/attribute"
 	"github.com/newrelic/infra-integrations-sdk/v3/data/metric"
 	"github.com/newrelic/infra-integrations-sdk/v3/log"
 	"github.com/newrelic/infra-integrations-sdk/v3/persist"
@@ -179,3 +181,67 @@ func TestCollectVhostMetrics(t *testing.T) {
 		assert.Equal(t, string(expected), string(actual))
 	}
 }
+
+func Test_setCounterMetrics(t *testing.T) {
+	existingArgs := args.GlobalArgs
+	defer func() {
+		args.GlobalArgs = existingArgs
+		persist.SetNow(time.Now)
+	}()
+	store := persist.NewInMemoryStore()
+	newSet := func(published float64) *metric.Set {
+		ms := metric.NewSet("RabbitmqQueueSample", store, attribute.Attr("entityName", "queue:vhost1/queue1"))
+		ms.Metrics["queue.messagesPublished"] = published
+		return ms
+	}
+
+	args.GlobalArgs.CountersSourceType = args.CountersAsGauge
+	ms := newSet(10)
+	setCounterMetrics(ms, consts.QueueType)
+	assert.Equal(t, float64(10), ms.Metrics["queue.messagesPublished"])
+	assert.NotContains(t, ms.Metrics, "queue.messagesPublishedRate")
+
+	args.GlobalArgs.CountersSourceType = args.CountersAsRate
+	ms = newSet(10)
+	setCounterMetrics(ms, consts.ExchangeType)
+	assert.Len(t, ms.Metrics, 3, "only the counters of the entity type are reported as rates")
+
+	args.GlobalArgs.CountersSourceType = args.CountersAsRate
+	args.GlobalArgs.LegacyCounterGauges = true
+	persist.SetNow(func() time.Time { return time.Unix(1000, 0) })
+	setCounterMetrics(newSet(10), consts.QueueType)
+	persist.SetNow(func() time.Time { return time.Unix(1010, 0) })
+	ms = newSet(60)
+	setCounterMetrics(ms, consts.QueueType)
+	assert.Equal(t, float64(60), ms.Metrics["queue.messagesPublished"])
+	assert.Equal(t, float64(5), ms.Metrics["queue.messagesPublishedRate"])
+
+	args.GlobalArgs.CountersSourceType = args.CountersAsDelta
+	args.GlobalArgs.LegacyCounterGauges = false
+	setCounterMetrics(newSet(60), consts.QueueType)
+	persist.SetNow(func() time.Time { return time.Unix(1020, 0) })
+	ms = newSet(100)
+	setCounterMetrics(ms, consts.QueueType)
+	assert.NotContains(t, ms.Metrics, "queue.messagesPublished")
+	assert.Equal(t, float64(40), ms.Metrics["queue.messagesPublishedDelta"])
+
+	persist.SetNow(func() time.Time { return time.Unix(1030, 0) })
+	ms = newSet(5)
+	setCounterMetrics(ms, consts.QueueType)
+	assert.NotContains(t, ms.Metrics, "queue.messagesPublishedDelta", "a reset counter has no delta")
+}
+
+func TestCollectEntityMetrics_QueueGroup(t *testing.T) {
+	i := testutils.GetTestingIntegration(t)
+	group := "orders.tenant-1"
+	CollectEntityMetrics(i, nil, "testClusterName", &data.QueueData{
+		Name:          "orders.tenant-1.shard-1",
+		Group:         &group,
+		GroupCaptures: map[string]string{"tenant": "tenant-1"},
+	})
+	if assert.Len(t, i.Entities, 1) && assert.Len(t, i.Entities[0].Metrics, 1) {
+		ms := i.Entities[0].Metrics[0]
+		assert.Equal(t, "orders.tenant-1", ms.Metrics["queue.group"])
+		assert.Equal(t, "tenant-1", ms.Metrics["queue.group.tenant"])
+	}
+}
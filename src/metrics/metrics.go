@@ -46,9 +46,13 @@ func CollectEntityMetrics(rabbitmqIntegration *integration.Integration, bindings
 
 		metricSet := entity.NewMetricSet(getSampleName(dataItem.EntityType()), metricNamespace...)
 		warnIfError(metricSet.MarshalMetrics(dataItem), "Error collecting metrics for [%s:%s]", dataItem.EntityType(), dataItem.EntityName())
+		setCounterMetrics(metricSet, dataItem.EntityType())
 
 		if queue, ok := dataItem.(*data.QueueData); ok {
 			populateBindingMetric(queue.Name, queue.Vhost, consts.QueueType, metricSet, bindingStats)
+			for capture, value := range queue.GroupCaptures {
+				setMetric(metricSet, "queue.group."+capture, value, metric.ATTRIBUTE)
+			}
 			if !args.GlobalArgs.DisableEntities {
 				queue.CollectInventory(entity, bindingStats)
 			}
@@ -106,3 +110,47 @@ func populateBindingMetric(entityName, vhost, entityType string, metricSet *metr
 	}
 	setMetric(metricSet, entityType+".bindings", count, metric.GAUGE)
 }
+
+// counterMetrics are the cumulative message counters of each entity type, reported as gauges by MarshalMetrics
+var counterMetrics = map[string][]string{
+	consts.QueueType: {
+		"queue.messagesAcknowledged",
+		"queue.messagesDeliveredAckMode",
+		"queue.sumMessagesDelivered",
+		"queue.messagesPublished",
+		"queue.messagesRedeliverGet",
+	},
+	consts.ExchangeType: {
+		"exchange.messagesPublishedPerChannel",
+		"exchange.messagesPublishedQueue",
+	},
+}
+
+// setCounterMetrics reports the cumulative counters as rates or deltas, with a Rate or Delta suffix, when
+// CountersSourceType asks so. The original gauges are removed unless LegacyCounterGauges is set.
+func setCounterMetrics(metricSet *metric.Set, entityType string) {
+	var suffix string
+	var sourceType metric.SourceType
+	switch args.GlobalArgs.CountersSourceType {
+	case args.CountersAsRate:
+		suffix, sourceType = "Rate", metric.PRATE
+	case args.CountersAsDelta:
+		suffix, sourceType = "Delta", metric.PDELTA
+	default:
+		return
+	}
+
+	for _, metricName := range counterMetrics[entityType] {
+		value, ok := metricSet.Metrics[metricName]
+		if !ok {
+			continue
+		}
+		if !args.GlobalArgs.LegacyCounterGauges {
+			delete(metricSet.Metrics, metricName)
+		}
+		if err := metricSet.SetMetric(metricName+suffix, value, sourceType); err != nil {
+			// a negative difference means the counter was reset, as it happens when the queue is recreated
+			log.Debug("Could not compute %s: %v", metricName+suffix, err)
+		}
+	}
+}
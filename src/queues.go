@@ -0,0 +1,166 @@
+package main
+
+import (
+	"sort"
+
+	"github.com/newrelic/nri-rabbitmq/src/args"
+	"github.com/newrelic/nri-rabbitmq/src/data"
+	"github.com/newrelic/nri-rabbitmq/src/data/consts"
+)
+
+// getFilteredQueues returns the queues that are not filtered by the configuration
+func getFilteredQueues(queuesData []*data.QueueData) []*data.QueueData {
+	queues := make([]*data.QueueData, 0, len(queuesData))
+	for _, queueData := range queuesData {
+		if args.GlobalArgs.IncludeEntity(queueData.Name, consts.QueueType, queueData.Vhost) &&
+			queueData.MatchesFilter(args.GlobalArgs.QueueFilterFor(queueData.Vhost)) &&
+			args.GlobalArgs.IncludeQueueNode(queueData.Node) {
+			queues = append(queues, queueData)
+		}
+	}
+	return queues
+}
+
+// setQueueGroups sets the group of the queues matching the QueueGroupTemplates
+func setQueueGroups(queues []*data.QueueData) {
+	if len(args.GlobalArgs.QueueGroupTemplates) == 0 {
+		return
+	}
+	for _, queue := range queues {
+		if group, captures, ok := args.GlobalArgs.QueueGroup(queue.Name); ok {
+			queue.Group = &group
+			queue.GroupCaptures = captures
+		}
+	}
+}
+
+// setGroupsNode sets the local node of the queue groups when only its queues are reported
+func setGroupsNode(groups []*data.QueueGroupData) {
+	if !args.GlobalArgs.LocalQueuesOnly {
+		return
+	}
+	for _, group := range groups {
+		group.Node = args.GlobalArgs.LocalNodeName
+	}
+}
+
+// getTopQueues returns the limit queues ranked first by QueuesRankBy, and the rest of them summarised per vhost.
+// Queues are ranked in descending order except for consumer_utilisation, where the least utilised queues come first.
+func getTopQueues(queues []*data.QueueData, limit int) ([]*data.QueueData, []*data.QueueGroupData) {
+	ranked := make([]*data.QueueData, len(queues))
+	copy(ranked, queues)
+	sort.SliceStable(ranked, func(i, j int) bool {
+		iValue, iOk := getQueueRank(ranked[i])
+		jValue, jOk := getQueueRank(ranked[j])
+		if iOk != jOk {
+			return iOk
+		}
+		if iValue != jValue {
+			if rankAscending() {
+				return iValue < jValue
+			}
+			return iValue > jValue
+		}
+		if ranked[i].Vhost != ranked[j].Vhost {
+			return ranked[i].Vhost < ranked[j].Vhost
+		}
+		return ranked[i].Name < ranked[j].Name
+	})
+	if len(ranked) <= limit {
+		return ranked, nil
+	}
+
+	groups := make(map[string]*data.QueueGroupData)
+	var others []*data.QueueGroupData
+	for _, queue := range ranked[limit:] {
+		group := groups[queue.Vhost]
+		if group == nil {
+			group = &data.QueueGroupData{Vhost: queue.Vhost, Name: data.OtherQueuesGroup, Type: data.QueueGroupTypeOther}
+			groups[queue.Vhost] = group
+			others = append(others, group)
+		}
+		group.Add(queue)
+	}
+	for _, group := range others {
+		group.Summarize()
+	}
+	sort.Slice(others, func(i, j int) bool {
+		return others[i].Vhost < others[j].Vhost
+	})
+	return ranked[:limit], others
+}
+
+// groupQueues summarises all the queues of each vhost, the queues matching each of the QueueGroupsRegexes and the
+// queues of each group set from the QueueGroupTemplates. The groups are told apart by their type, as a template can
+// produce the name of a regex or of the group of all the queues.
+func groupQueues(queues []*data.QueueData) []*data.QueueGroupData {
+	type groupKey struct {
+		vhost, groupType, name string
+	}
+	groups := make(map[groupKey]*data.QueueGroupData)
+	var result []*data.QueueGroupData
+	addToGroup := func(queue *data.QueueData, groupType, name string) {
+		key := groupKey{queue.Vhost, groupType, name}
+		group := groups[key]
+		if group == nil {
+			group = &data.QueueGroupData{Vhost: queue.Vhost, Name: name, Type: groupType}
+			groups[key] = group
+			result = append(result, group)
+		}
+		group.Add(queue)
+	}
+
+	for _, queue := range queues {
+		addToGroup(queue, data.QueueGroupTypeAll, data.AllQueuesGroup)
+		for _, regex := range args.GlobalArgs.QueueGroupsRegexes {
+			if regex.MatchString(queue.Name) {
+				addToGroup(queue, data.QueueGroupTypeRegex, regex.String())
+			}
+		}
+		if queue.Group != nil {
+			addToGroup(queue, data.QueueGroupTypeTemplate, *queue.Group)
+		}
+	}
+	for _, group := range result {
+		group.Summarize()
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		return result[i].Vhost < result[j].Vhost
+	})
+	return result
+}
+
+// rankAscending returns true when a lower value ranks the queue higher, as a low consumer utilisation means the
+// consumers can't keep up with the queue
+func rankAscending() bool {
+	return args.GlobalArgs.QueuesRankBy == args.RankByConsumerUtilisation
+}
+
+// getQueueRank returns the value the queue is ranked by, queues without it are ranked last
+func getQueueRank(queue *data.QueueData) (float64, bool) {
+	switch args.GlobalArgs.QueuesRankBy {
+	case args.RankByMessagesUnacknowledged:
+		return int64Rank(queue.MessagesUnacknowledged)
+	case args.RankByPublishRate:
+		return float64Rank(queue.MessageStats.PublishDetails.Rate)
+	case args.RankByMemory:
+		return int64Rank(queue.Memory)
+	case args.RankByConsumerUtilisation:
+		return float64Rank(queue.ConsumerUtilisation)
+	}
+	return int64Rank(queue.Messages)
+}
+
+func int64Rank(value *int64) (float64, bool) {
+	if value == nil {
+		return 0, false
+	}
+	return float64(*value), true
+}
+
+func float64Rank(value *float64) (float64, bool) {
+	if value == nil {
+		return 0, false
+	}
+	return *value, true
+}
@@ -0,0 +1,274 @@
+package main
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/newrelic/nri-rabbitmq/src/args"
+	"github.com/newrelic/nri-rabbitmq/src/data"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newQueue(vhost, name string, messages int64) *data.QueueData {
+	return &data.QueueData{Name: name, Vhost: vhost, Messages: &messages}
+}
+
+func Test_getTopQueues(t *testing.T) {
+	args.GlobalArgs = args.RabbitMQArguments{QueuesRankBy: args.RankByMessages}
+	queues := []*data.QueueData{
+		newQueue("vhost1", "small", 1),
+		newQueue("vhost1", "big", 100),
+		{Name: "unknown", Vhost: "vhost2"},
+		newQueue("vhost2", "medium", 50),
+		newQueue("vhost2", "tie-b", 10),
+		newQueue("vhost2", "tie-a", 10),
+	}
+
+	top, others := getTopQueues(queues, 3)
+	var names []string
+	for _, q := range top {
+		names = append(names, q.Name)
+	}
+	assert.Equal(t, []string{"big", "medium", "tie-a"}, names)
+	if assert.Len(t, others, 2) {
+		assertQueueGroup(t, "vhost1", data.OtherQueuesGroup, 1, 1, others[0])
+		assertQueueGroup(t, "vhost2", data.OtherQueuesGroup, 2, 10, others[1])
+		assert.Equal(t, int64(10), others[1].MessagesMax)
+	}
+
+	top, others = getTopQueues(queues[:2], 3)
+	assert.Len(t, top, 2)
+	assert.Empty(t, others)
+}
+
+func Test_getTopQueues_ConsumerUtilisation(t *testing.T) {
+	args.GlobalArgs = args.RabbitMQArguments{QueuesRankBy: args.RankByConsumerUtilisation}
+	newUtilisedQueue := func(name string, utilisation float64) *data.QueueData {
+		queue := newQueue("vhost1", name, 1)
+		queue.ConsumerUtilisation = &utilisation
+		return queue
+	}
+	queues := []*data.QueueData{
+		newUtilisedQueue("busy", 1),
+		{Name: "unknown", Vhost: "vhost1"},
+		newUtilisedQueue("idle", 0.1),
+		newUtilisedQueue("half", 0.5),
+	}
+
+	top, others := getTopQueues(queues, 2)
+	var names []string
+	for _, q := range top {
+		names = append(names, q.Name)
+	}
+	assert.Equal(t, []string{"idle", "half"}, names, "the least utilised queues are ranked first")
+	if assert.Len(t, others, 1) {
+		assertQueueGroup(t, "vhost1", data.OtherQueuesGroup, 2, 1, others[0])
+	}
+
+	top, _ = getTopQueues(queues, 4)
+	assert.Equal(t, "unknown", top[3].Name, "queues without a consumer utilisation are ranked last")
+}
+
+func Test_getQueueRank(t *testing.T) {
+	memory, unacked, rate := int64(2048), int64(7), 1.5
+	queue := newQueue("vhost1", "queue1", 10)
+	queue.Memory = &memory
+	queue.MessagesUnacknowledged = &unacked
+	queue.ConsumerUtilisation = &rate
+	queue.MessageStats.PublishDetails.Rate = &rate
+
+	tests := map[string]float64{
+		args.RankByMessages:               10,
+		args.RankByMessagesUnacknowledged: 7,
+		args.RankByPublishRate:            1.5,
+		args.RankByMemory:                 2048,
+		args.RankByConsumerUtilisation:    1.5,
+	}
+	for rankBy, expected := range tests {
+		args.GlobalArgs = args.RabbitMQArguments{QueuesRankBy: rankBy}
+		value, ok := getQueueRank(queue)
+		assert.True(t, ok, rankBy)
+		assert.Equal(t, expected, value, rankBy)
+	}
+
+	_, ok := getQueueRank(&data.QueueData{})
+	assert.False(t, ok)
+}
+
+func Test_getMetricEntities_TopQueues(t *testing.T) {
+	args.GlobalArgs = args.RabbitMQArguments{QueuesMaxLimit: 1, QueuesLimitMode: args.QueuesLimitDrop, QueuesRankBy: args.RankByMessages}
+	rabbitData := &allData{
+		nodes:  []*data.NodeData{{Name: "node1"}},
+		queues: []*data.QueueData{newQueue("vhost1", "queue1", 1), newQueue("vhost1", "queue2", 2)},
+	}
+	assert.Len(t, getMetricEntities(rabbitData), 1, "all queues are dropped past the limit")
+
+	args.GlobalArgs.QueuesLimitMode = args.QueuesLimitTop
+	entities := getMetricEntities(rabbitData)
+	if assert.Len(t, entities, 3) {
+		assert.Equal(t, rabbitData.queues[1], entities[1])
+		assertQueueGroup(t, "vhost1", data.OtherQueuesGroup, 1, 1, entities[2].(*data.QueueGroupData))
+	}
+}
+
+func Test_getMetricEntities_QueuesAggregation(t *testing.T) {
+	args.GlobalArgs = args.RabbitMQArguments{QueuesAggregation: args.QueuesAggregationVhost}
+	rabbitData := &allData{
+		nodes:  []*data.NodeData{{Name: "node1"}},
+		queues: []*data.QueueData{newQueue("vhost1", "queue1", 1), newQueue("vhost1", "queue2", 2)},
+	}
+	entities := getMetricEntities(rabbitData)
+	if assert.Len(t, entities, 4) {
+		assertQueueGroup(t, "vhost1", data.AllQueuesGroup, 2, 3, entities[1].(*data.QueueGroupData))
+		assert.Equal(t, rabbitData.queues[0], entities[2])
+	}
+
+	args.GlobalArgs.QueuesAggregation = args.QueuesAggregationOnly
+	entities = getMetricEntities(rabbitData)
+	if assert.Len(t, entities, 2, "queues are not reported") {
+		assertQueueGroup(t, "vhost1", data.AllQueuesGroup, 2, 3, entities[1].(*data.QueueGroupData))
+	}
+}
+
+func Test_groupQueues(t *testing.T) {
+	args.GlobalArgs = args.RabbitMQArguments{QueueGroupsRegexes: []*regexp.Regexp{regexp.MustCompile("^orders\\."), regexp.MustCompile("dlq$")}}
+	queues := []*data.QueueData{
+		newQueue("vhost2", "orders.eu", 5),
+		newQueue("vhost1", "orders.eu", 1),
+		newQueue("vhost1", "orders.us", 2),
+		newQueue("vhost1", "orders.dlq", 4),
+		newQueue("vhost1", "billing", 8),
+	}
+
+	groups := groupQueues(queues)
+	if assert.Len(t, groups, 5) {
+		assertQueueGroup(t, "vhost1", data.AllQueuesGroup, 4, 15, groups[0])
+		assertQueueGroup(t, "vhost1", "^orders\\.", 3, 7, groups[1])
+		assertQueueGroup(t, "vhost1", "dlq$", 1, 4, groups[2])
+		assertQueueGroup(t, "vhost2", data.AllQueuesGroup, 1, 5, groups[3])
+		assertQueueGroup(t, "vhost2", "^orders\\.", 1, 5, groups[4])
+		assert.Equal(t, int64(8), groups[0].MessagesMax)
+		assert.Equal(t, int64(4), groups[1].MessagesMax)
+	}
+}
+
+func Test_setQueueGroups(t *testing.T) {
+	argList := args.ArgumentList{Hostname: "foo", Port: 8000, QueueGroupTemplates: `[{"regex": "^orders\\.(?P<tenant>[^.]+)\\.shard-\\d+$", "group": "orders.{tenant}"}]`}
+	require.NoError(t, args.SetGlobalArgs(argList))
+	args.GlobalArgs.QueuesAggregation = args.QueuesAggregationVhost
+	queues := []*data.QueueData{
+		newQueue("vhost1", "orders.tenant-1.shard-1", 1),
+		newQueue("vhost1", "orders.tenant-1.shard-2", 2),
+		newQueue("vhost1", "orders.tenant-2.shard-1", 4),
+		newQueue("vhost1", "billing", 8),
+	}
+
+	setQueueGroups(queues)
+	if assert.NotNil(t, queues[0].Group) {
+		assert.Equal(t, "orders.tenant-1", *queues[0].Group)
+		assert.Equal(t, map[string]string{"tenant": "tenant-1"}, queues[0].GroupCaptures)
+	}
+	assert.Nil(t, queues[3].Group)
+
+	groups := groupQueues(queues)
+	if assert.Len(t, groups, 3) {
+		assertQueueGroup(t, "vhost1", data.AllQueuesGroup, 4, 15, groups[0])
+		assertQueueGroup(t, "vhost1", "orders.tenant-1", 2, 3, groups[1])
+		assertQueueGroup(t, "vhost1", "orders.tenant-2", 1, 4, groups[2])
+	}
+}
+
+func Test_groupQueues_SameNames(t *testing.T) {
+	args.GlobalArgs = args.RabbitMQArguments{QueueGroupsRegexes: []*regexp.Regexp{regexp.MustCompile("^orders")}}
+	queues := []*data.QueueData{
+		newQueue("vhost1", "orders.eu", 1),
+		newQueue("vhost1", "billing", 2),
+	}
+	all, orders := data.AllQueuesGroup, "^orders"
+	queues[0].Group = &all
+	queues[1].Group = &orders
+
+	groups := groupQueues(queues)
+	if assert.Len(t, groups, 4, "a template producing the name of another group doesn't merge with it") {
+		assertQueueGroup(t, "vhost1", data.AllQueuesGroup, 2, 3, groups[0])
+		assertQueueGroup(t, "vhost1", "^orders", 1, 1, groups[1])
+		assert.Equal(t, data.QueueGroupTypeRegex, groups[1].Type)
+		assert.Equal(t, data.QueueGroupTypeTemplate, groups[2].Type)
+		assert.Equal(t, data.AllQueuesGroup, groups[2].Name)
+		assert.Equal(t, 1, groups[2].Queues)
+		assert.Equal(t, data.QueueGroupTypeTemplate, groups[3].Type)
+		assert.Equal(t, "^orders", groups[3].Name)
+		assert.Equal(t, int64(2), groups[3].Messages)
+	}
+}
+
+func Test_getMetricEntities_PropertyFilters(t *testing.T) {
+	no := false
+	args.GlobalArgs = args.RabbitMQArguments{
+		QueuesFilter:    &args.QueueFilter{Exclusive: &no},
+		ExchangesFilter: &args.ExchangeFilter{ExcludeTypes: []string{"x-delayed-message"}},
+	}
+	rabbitData := &allData{
+		nodes:     []*data.NodeData{{Name: "node1"}},
+		exchanges: []*data.ExchangeData{{Name: "delayed", Type: "x-delayed-message"}, {Name: "orders", Type: "topic"}},
+		queues:    []*data.QueueData{{Name: "amq.gen-1", Exclusive: true}, {Name: "orders"}},
+	}
+	entities := getMetricEntities(rabbitData)
+	if assert.Len(t, entities, 3) {
+		assert.Equal(t, rabbitData.exchanges[1], entities[1])
+		assert.Equal(t, rabbitData.queues[1], entities[2])
+	}
+}
+
+func Test_getMetricEntities_NoClusterEntities(t *testing.T) {
+	args.GlobalArgs = args.RabbitMQArguments{ClusterEntities: args.ClusterEntitiesNever}
+	rabbitData := &allData{
+		nodes:     []*data.NodeData{{Name: "node1"}, {Name: "node2"}},
+		exchanges: []*data.ExchangeData{{Name: "orders"}},
+		queues:    []*data.QueueData{{Name: "orders"}},
+		cluster:   &data.ClusterData{Name: "cluster"},
+	}
+	entities := getMetricEntities(rabbitData)
+	assert.Equal(t, []data.EntityData{rabbitData.nodes[0], rabbitData.nodes[1]}, entities)
+}
+
+func Test_getMetricEntities_LocalQueuesOnly(t *testing.T) {
+	args.GlobalArgs = args.RabbitMQArguments{
+		ClusterEntities:   args.ClusterEntitiesNever,
+		LocalQueuesOnly:   true,
+		LocalNodeName:     "rabbit@a",
+		QueuesAggregation: args.QueuesAggregationVhost,
+	}
+	rabbitData := &allData{
+		nodes:     []*data.NodeData{{Name: "rabbit@a"}},
+		exchanges: []*data.ExchangeData{{Name: "orders"}},
+		queues: []*data.QueueData{
+			{Name: "orders", Vhost: "vhost1", Node: "rabbit@a"},
+			{Name: "billing", Vhost: "vhost1", Node: "rabbit@b"},
+		},
+	}
+	entities := getMetricEntities(rabbitData)
+	if assert.Len(t, entities, 3, "the exchanges are not reported, the local queues are") {
+		assert.Equal(t, rabbitData.nodes[0], entities[0])
+		group := entities[1].(*data.QueueGroupData)
+		assertQueueGroup(t, "vhost1", data.AllQueuesGroup, 1, 0, group)
+		assert.Equal(t, "rabbit@a", group.Node)
+		assert.Equal(t, rabbitData.queues[0], entities[2])
+	}
+}
+
+func assertQueueGroup(t *testing.T, vhost, name string, queues int, messages int64, group *data.QueueGroupData) {
+	t.Helper()
+	assert.Equal(t, vhost, group.Vhost)
+	assert.Equal(t, name, group.Name)
+	assert.Equal(t, queues, group.Queues)
+	assert.Equal(t, messages, group.Messages)
+	switch name {
+	case data.AllQueuesGroup:
+		assert.Equal(t, data.QueueGroupTypeAll, group.Type)
+	case data.OtherQueuesGroup:
+		assert.Equal(t, data.QueueGroupTypeOther, group.Type)
+	}
+}
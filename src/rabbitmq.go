@@ -1,22 +1,27 @@
 package main
 
 import (
+	"errors"
 	"fmt"
 	"net/url"
 	"os"
 	"runtime"
 	"strings"
+	"time"
 
+	"github.com/newrelic/nri-rabbitmq/src/alerts"
 	"github.com/newrelic/nri-rabbitmq/src/args"
 	"github.com/newrelic/nri-rabbitmq/src/client"
 	"github.com/newrelic/nri-rabbitmq/src/data"
 	"github.com/newrelic/nri-rabbitmq/src/data/consts"
 	"github.com/newrelic/nri-rabbitmq/src/inventory"
 	"github.com/newrelic/nri-rabbitmq/src/metrics"
+	"github.com/newrelic/nri-rabbitmq/src/state"
 
 	"github.com/newrelic/infra-integrations-sdk/v3/data/event"
 	"github.com/newrelic/infra-integrations-sdk/v3/integration"
 	"github.com/newrelic/infra-integrations-sdk/v3/log"
+	"github.com/newrelic/infra-integrations-sdk/v3/persist"
 )
 
 const (
@@ -24,6 +29,8 @@ const (
 	success         = "ok"
 	NotRunning      = "not running"
 	RunningUnknown  = "unknown"
+	// stateTTL is how long the state used to detect transitions is kept when the integration stops running
+	stateTTL = 24 * time.Hour
 )
 
 var (
@@ -54,23 +61,62 @@ func main() {
 
 	log.SetupLogging(args.GlobalArgs.Verbose)
 
+	stateStore, err := newStateStore(rabbitmqIntegration, argList)
+	exitIfError(err, "Error creating state store: %v")
+
 	rabbitData := getNeededData()
 	clusterName := rabbitData.overview.ClusterName
 
+	localNodeName := rabbitData.localNodeName
+	clusterEntities := args.GlobalArgs.ReportsClusterEntities()
+
+	// the partition handling is read from the local configuration files, for the cluster sample and partition events
+	var partitionHandling string
+	if localNodeName != "" && ((args.GlobalArgs.HasMetrics() && clusterEntities && clusterName != "") || args.GlobalArgs.HasEvents()) {
+		partitionHandling = inventory.GetPartitionHandling(rabbitData.nodes, localNodeName)
+	}
+
 	if args.GlobalArgs.HasMetrics() {
-		metrics.CollectVhostMetrics(rabbitmqIntegration, rabbitData.vhosts, rabbitData.connections, clusterName)
+		var vhosts []*data.VhostData
+		if clusterEntities {
+			vhosts = rabbitData.vhosts
+			if clusterName != "" {
+				rabbitData.cluster = data.NewClusterData(clusterName, rabbitData.nodes, partitionHandling)
+			}
+			metrics.CollectVhostMetrics(rabbitmqIntegration, vhosts, rabbitData.connections, clusterName)
+		}
+
+		// rates are computed before the queues are limited, as they can be used to rank them
+		data.ComputeMissingRates(stateStore, rabbitData.getCounterEntities()...)
 
 		metricEntities := getMetricEntities(rabbitData)
+		// trends are only computed for the queues reported, so the store doesn't keep an entry for every queue
+		data.ComputeQueueTrends(stateStore, metricEntities...)
 		metrics.CollectEntityMetrics(rabbitmqIntegration, rabbitData.bindings, clusterName, metricEntities...)
+
+		if args.GlobalArgs.HasEvents() {
+			alerts.EvaluateRules(rabbitmqIntegration, stateStore, clusterName, vhosts, metricEntities...)
+		}
 	}
 
 	if args.GlobalArgs.HasInventory() {
-		inventory.CollectInventory(rabbitmqIntegration, rabbitData.nodes, clusterName)
+		inventory.CollectInventory(rabbitmqIntegration, rabbitData.nodes, localNodeName, rabbitData.features, clusterName)
 	}
 
 	if args.GlobalArgs.HasEvents() {
-		alivenessTest(rabbitmqIntegration, rabbitData.aliveness, clusterName)
-		healthcheckTest(rabbitmqIntegration, rabbitData.nodes, clusterName)
+		healthcheckTest(rabbitmqIntegration, stateStore, rabbitData.nodes, clusterName)
+		alarmTest(rabbitmqIntegration, stateStore, rabbitData.nodes, rabbitData.connections, clusterName)
+		partitionTest(rabbitmqIntegration, stateStore, rabbitData.nodes, partitionHandling, clusterName)
+		if clusterEntities {
+			alivenessTest(rabbitmqIntegration, stateStore, rabbitData.aliveness, clusterName)
+			membershipTest(rabbitmqIntegration, stateStore, rabbitData.nodes, clusterName)
+			linkStateTest(rabbitmqIntegration, stateStore, rabbitData.getLinks(), clusterName)
+		}
+		inventory.CollectFeatureEvents(rabbitmqIntegration, stateStore, rabbitData.nodes, localNodeName, rabbitData.features, clusterName)
+	}
+
+	if err = stateStore.Save(); err != nil {
+		log.Error("Error saving state store: %v", err)
 	}
 
 	if len(rabbitmqIntegration.Entities) > 0 {
@@ -82,6 +128,18 @@ func main() {
 	}
 }
 
+// newStateStore creates the store that keeps the state between runs needed to raise events only on transitions.
+// It's kept apart from the integration's metrics store, whose entries expire after CacheTTL.
+func newStateStore(rabbitmqIntegration *integration.Integration, argList args.ArgumentList) (persist.Storer, error) {
+	logger := log.NewStdErr(argList.Verbose)
+	storePath, err := persist.NewStorePath(integrationName+"-state", rabbitmqIntegration.CreateUniqueID(), argList.TempDir, logger, stateTTL)
+	if err != nil {
+		return nil, err
+	}
+	storePath.CleanOldFiles()
+	return persist.NewFileStore(storePath.GetFilePath(), logger, stateTTL)
+}
+
 type allData struct {
 	overview    *data.OverviewData
 	vhosts      []*data.VhostData
@@ -92,22 +150,74 @@ type allData struct {
 	bindings    []*data.BindingData
 	healthcheck []*data.NodeTest
 	aliveness   []*data.VhostTest
+	shovels     []*data.ShovelData
+	federation  []*data.FederationLinkData
+	features    *data.FeaturesData
+	cluster     *data.ClusterData
+	// localNodeName is empty if it's not needed or it can't be determined
+	localNodeName string
+}
+
+// getLinks returns the shovels and federation links together
+func (rabbitData *allData) getLinks() []data.LinkData {
+	links := make([]data.LinkData, 0, len(rabbitData.shovels)+len(rabbitData.federation))
+	for _, v := range rabbitData.shovels {
+		links = append(links, v)
+	}
+	for _, v := range rabbitData.federation {
+		links = append(links, v)
+	}
+	return links
+}
+
+// getCounterEntities returns the exchanges and the queues not filtered by the configuration, whose message counters
+// are used to compute rates
+func (rabbitData *allData) getCounterEntities() []data.EntityData {
+	queues := getFilteredQueues(rabbitData.queues)
+	entities := make([]data.EntityData, 0, len(rabbitData.exchanges)+len(queues))
+	for _, v := range rabbitData.exchanges {
+		entities = append(entities, v)
+	}
+	for _, v := range queues {
+		entities = append(entities, v)
+	}
+	return entities
 }
 
 func getNeededData() *allData {
 	rabbitData := new(allData)
 	exitIfError(client.CollectEndpoint(client.NodesEndpoint, &rabbitData.nodes), "Error collecting Node data: %v")
 	exitIfError(client.CollectEndpoint(client.OverviewEndpoint, &rabbitData.overview), "Error collecting Overview data: %v")
+	rabbitData.localNodeName = resolveLocalNode(rabbitData)
+
+	// the cluster-wide endpoints are only collected by the instances reporting them
+	clusterEntities := args.GlobalArgs.ReportsClusterEntities()
 	if args.GlobalArgs.HasMetrics() {
 		exitIfError(client.CollectEndpoint(client.ConnectionsEndpoint, &rabbitData.connections), "Error collecting Connections data: %v")
-		exitIfError(client.CollectEndpoint(client.BindingsEndpoint, &rabbitData.bindings), "Error collecting Bindings data: %v")
-		exitIfError(client.CollectEndpoint(client.VhostsEndpoint, &rabbitData.vhosts), "Error collecting Vhost data: %v")
-		exitIfError(client.CollectEndpoint(client.QueuesEndpoint, &rabbitData.queues), "Error collecting Queue data: %v")
-		exitIfError(client.CollectEndpoint(client.ExchangesEndpoint, &rabbitData.exchanges), "Error collecting Exchange data: %v")
+		if clusterEntities || args.GlobalArgs.LocalQueuesOnly {
+			exitIfError(client.CollectEndpoint(client.BindingsEndpoint, &rabbitData.bindings), "Error collecting Bindings data: %v")
+			exitIfError(client.CollectEndpoint(client.QueuesEndpoint, &rabbitData.queues), "Error collecting Queue data: %v")
+		}
+		if clusterEntities {
+			exitIfError(client.CollectEndpoint(client.VhostsEndpoint, &rabbitData.vhosts), "Error collecting Vhost data: %v")
+			exitIfError(client.CollectEndpoint(client.ExchangesEndpoint, &rabbitData.exchanges), "Error collecting Exchange data: %v")
+		}
 	} else if args.GlobalArgs.HasEvents() {
-		exitIfError(client.CollectEndpoint(client.VhostsEndpoint, &rabbitData.vhosts), "Error collecting Vhost data: %v")
+		if clusterEntities {
+			exitIfError(client.CollectEndpoint(client.VhostsEndpoint, &rabbitData.vhosts), "Error collecting Vhost data: %v")
+		}
+		exitIfError(client.CollectEndpoint(client.ConnectionsEndpoint, &rabbitData.connections), "Error collecting Connections data: %v")
 	}
-	if args.GlobalArgs.HasEvents() {
+	if (args.GlobalArgs.HasMetrics() || args.GlobalArgs.HasEvents()) && clusterEntities {
+		warnIfOptionalError(client.CollectEndpoint(client.ShovelsEndpoint, &rabbitData.shovels), "Error collecting Shovel data: %v")
+		warnIfOptionalError(client.CollectEndpoint(client.FederationLinksEndpoint, &rabbitData.federation), "Error collecting Federation Link data: %v")
+	}
+	if args.GlobalArgs.HasInventory() || args.GlobalArgs.HasEvents() {
+		rabbitData.features = new(data.FeaturesData)
+		warnIfOptionalError(client.CollectEndpoint(client.FeatureFlagsEndpoint, &rabbitData.features.FeatureFlags), "Error collecting Feature Flag data: %v")
+		warnIfOptionalError(client.CollectEndpoint(client.DeprecatedFeaturesUsedEndpoint, &rabbitData.features.DeprecatedFeatures), "Error collecting Deprecated Feature data: %v")
+	}
+	if args.GlobalArgs.HasEvents() && clusterEntities {
 		getEventData(rabbitData)
 	}
 	return rabbitData
@@ -131,24 +241,97 @@ func getEventData(rabbitData *allData) {
 	}
 }
 
+// resolveLocalNode returns the local node name when it's needed, and sets the arguments depending on it
+func resolveLocalNode(rabbitData *allData) string {
+	var localNodeName string
+	localOnly := args.GlobalArgs.LocalNodeOnly || args.GlobalArgs.LocalQueuesOnly
+	if args.GlobalArgs.HasInventory() || args.GlobalArgs.HasEvents() || localOnly || args.GlobalArgs.ClusterEntities == args.ClusterEntitiesAuto {
+		localNodeName = getLocalNodeName(rabbitData)
+	}
+	if localOnly {
+		if localNodeName == "" {
+			log.Error("The local node name could not be determined, no node or queue is reported as LocalNodeOnly or LocalQueuesOnly is set. Use NodeNameOverride to set it.")
+		}
+		args.GlobalArgs.LocalNodeName = localNodeName
+	}
+	// with LocalNodeOnly, the cluster collector also reports the running status of the other nodes
+	if args.GlobalArgs.ClusterEntities == args.ClusterEntitiesAuto || args.GlobalArgs.LocalNodeOnly {
+		args.GlobalArgs.ClusterCollector = isClusterCollector(rabbitData.nodes, localNodeName)
+	}
+	return localNodeName
+}
+
+// getLocalNodeName returns the name of the node running alongside the integration, or an empty string if it can't be determined
+func getLocalNodeName(rabbitData *allData) string {
+	if len(rabbitData.nodes) == 0 {
+		return ""
+	}
+	nodeName, err := inventory.GetLocalNodeName(rabbitData.nodes, rabbitData.overview)
+	if err != nil {
+		log.Error("Error getting local node name: %s", err)
+		return ""
+	}
+	return nodeName
+}
+
 func getMetricEntities(apiData *allData) []data.EntityData {
 	i := 0
 	// Make the length the size of nodes and exchanges but capacity the length + size of queues. This is to accommodate the chance that there are more
 	// queues than can be collected.
 	dataItems := make([]data.EntityData, len(apiData.nodes)+len(apiData.exchanges), len(apiData.nodes)+len(apiData.exchanges)+len(apiData.queues))
 
+	clusterEntities := args.GlobalArgs.ReportsClusterEntities()
 	for _, v := range apiData.nodes {
 		dataItems[i] = v
 		i++
 	}
-	for _, v := range apiData.exchanges {
-		dataItems[i] = v
-		i++
+	if clusterEntities {
+		for _, v := range apiData.exchanges {
+			if v.MatchesFilter(args.GlobalArgs.ExchangeFilterFor(v.Vhost)) {
+				dataItems[i] = v
+				i++
+			}
+		}
+	}
+	dataItems = dataItems[:i]
+	if clusterEntities {
+		for _, v := range apiData.getLinks() {
+			dataItems = append(dataItems, v)
+		}
+		if apiData.cluster != nil {
+			dataItems = append(dataItems, apiData.cluster)
+		}
+	} else if !args.GlobalArgs.LocalQueuesOnly {
+		return dataItems
+	}
+
+	queues := getFilteredQueues(apiData.queues)
+	setQueueGroups(queues)
+	if aggregation := args.GlobalArgs.QueuesAggregation; aggregation == args.QueuesAggregationVhost || aggregation == args.QueuesAggregationOnly {
+		groups := groupQueues(queues)
+		setGroupsNode(groups)
+		for _, v := range groups {
+			dataItems = append(dataItems, v)
+		}
+		if args.GlobalArgs.QueuesAggregation == args.QueuesAggregationOnly {
+			return dataItems
+		}
 	}
 
-	queueLength := getFilteredQueueCount(apiData.queues)
-	if queueLength > args.GlobalArgs.QueuesMaxLimit && args.GlobalArgs.QueuesMaxLimit != 0 {
-		log.Error("There are %d queues in collection, the maximum amount of queues to collect is %d. Use the queue whitelist or regex configuration parameter to limit collection size.", queueLength, args.GlobalArgs.QueuesMaxLimit)
+	if len(queues) > args.GlobalArgs.QueuesMaxLimit && args.GlobalArgs.QueuesMaxLimit != 0 {
+		if args.GlobalArgs.QueuesLimitMode != args.QueuesLimitTop {
+			log.Error("There are %d queues in collection, the maximum amount of queues to collect is %d. Use the queue whitelist or regex configuration parameter to limit collection size.", len(queues), args.GlobalArgs.QueuesMaxLimit)
+			return dataItems
+		}
+		topQueues, others := getTopQueues(queues, args.GlobalArgs.QueuesMaxLimit)
+		setGroupsNode(others)
+		log.Warn("There are %d queues in collection, only the %d first by %s are collected and the rest are summarised per vhost.", len(queues), args.GlobalArgs.QueuesMaxLimit, args.GlobalArgs.QueuesRankBy)
+		for _, v := range topQueues {
+			dataItems = append(dataItems, v)
+		}
+		for _, v := range others {
+			dataItems = append(dataItems, v)
+		}
 		return dataItems
 	}
 
@@ -157,23 +340,12 @@ func getMetricEntities(apiData *allData) []data.EntityData {
 		log.Warn("QueuesMaxLimit has been disabled (=0) but the entities generation has not been disabled (DisableEntities) this could cause huge time and memory increase when metrics are processed by the Agent")
 	}
 
-	for _, v := range apiData.queues {
+	for _, v := range queues {
 		dataItems = append(dataItems, v)
 	}
 	return dataItems
 }
 
-func getFilteredQueueCount(queuesData []*data.QueueData) int {
-	queueCount := 0
-	for _, queueData := range queuesData {
-		if args.GlobalArgs.IncludeEntity(queueData.Name, "queue", queueData.Vhost) {
-			queueCount++
-		}
-	}
-
-	return queueCount
-}
-
 func exitIfError(err error, format string, args ...interface{}) {
 	if err != nil {
 		log.Error(format, append(args, err))
@@ -181,36 +353,61 @@ func exitIfError(err error, format string, args ...interface{}) {
 	}
 }
 
+// warnIfOptionalError logs errors from endpoints that may not be available, such as the ones served by optional plugins
+func warnIfOptionalError(err error, format string, args ...interface{}) {
+	if err == nil {
+		return
+	}
+	if errors.Is(err, client.ErrEndpointNotFound) {
+		log.Debug(format, append(args, err)...)
+		return
+	}
+	log.Warn(format, append(args, err)...)
+}
+
 func exitOnError(err error) {
 	if err != nil {
 		os.Exit(-1)
 	}
 }
 
-func alivenessTest(rabbitmqIntegration *integration.Integration, vhostTests []*data.VhostTest, clusterName string) {
+func alivenessTest(rabbitmqIntegration *integration.Integration, store persist.Storer, vhostTests []*data.VhostTest, clusterName string) {
 	if rabbitmqIntegration != nil {
 		for _, vhostTest := range vhostTests {
-			if vhostTest.Test.Status != success {
-				e, _, err := data.CreateEntity(rabbitmqIntegration, vhostTest.Vhost.Name, consts.VhostType, vhostTest.Vhost.Name, clusterName)
-				if err != nil {
-					log.Error("Error creating vhost entity [%s]: %v", vhostTest.Vhost.Name, err)
-					continue
-				}
+			failing := vhostTest.Test.Status != success
+			key := "aliveness/" + vhostTest.Vhost.Name
+			change, outage := state.Check(store, key, failing)
+			if change == state.NoTransition {
+				continue
+			}
 
-				// Don't add events for the entity if we are skipping its collection
-				if e != nil {
-					description := fmt.Sprintf("Response [%s] for vhost [%s]: %s", vhostTest.Test.Status, vhostTest.Vhost.Name, vhostTest.Test.Reason)
-					exitIfError(e.AddEvent(event.New(description, "integration")), "Error adding event: %v")
+			e, _, err := data.CreateEntity(rabbitmqIntegration, vhostTest.Vhost.Name, consts.VhostType, vhostTest.Vhost.Name, clusterName)
+			if err != nil {
+				log.Error("Error creating vhost entity [%s]: %v", vhostTest.Vhost.Name, err)
+				continue
+			}
+
+			// Don't add events for the entity if we are skipping its collection
+			if e != nil {
+				description := fmt.Sprintf("Response [%s] for vhost [%s]: %s", vhostTest.Test.Status, vhostTest.Vhost.Name, vhostTest.Test.Reason)
+				if change == state.Recovered {
+					description = fmt.Sprintf("Response [%s] for vhost [%s] after failing for %s", vhostTest.Test.Status, vhostTest.Vhost.Name, outage)
 				}
+				exitIfError(e.AddEvent(event.New(description, "integration")), "Error adding event: %v")
 			}
+			state.Record(store, key, change)
 		}
 	}
 }
 
-func healthcheckTest(rabbitmqIntegration *integration.Integration, nodes []*data.NodeData, clusterName string) {
+// healthcheckTest adds events when a node stops running and when it recovers
+func healthcheckTest(rabbitmqIntegration *integration.Integration, store persist.Storer, nodes []*data.NodeData, clusterName string) {
 	if rabbitmqIntegration != nil {
 		for _, node := range nodes {
-			if node.Running != nil && *node.Running {
+			failing := node.Running == nil || !*node.Running
+			key := "healthcheck/" + node.Name
+			change, outage := state.Check(store, key, failing)
+			if change == state.NoTransition {
 				continue
 			}
 
@@ -219,17 +416,21 @@ func healthcheckTest(rabbitmqIntegration *integration.Integration, nodes []*data
 				running = RunningUnknown
 			}
 
-			e, _, err := node.GetEntity(rabbitmqIntegration, clusterName)
+			e, _, err := node.GetStatusEntity(rabbitmqIntegration, clusterName)
 			if err != nil {
 				log.Error("Error creating node entity [%s]: %v", node.Name, err)
-				return
+				continue
 			}
 
 			// Don't add events for the entity if we are skipping its collection
 			if e != nil {
 				description := fmt.Sprintf("Response is [%s] for node [%s] running status", running, node.Name)
+				if change == state.Recovered {
+					description = fmt.Sprintf("Response is [running] for node [%s] running status after not running for %s", node.Name, outage)
+				}
 				exitIfError(e.AddEvent(event.New(description, "integration")), "Error adding event: %v")
 			}
+			state.Record(store, key, change)
 		}
 	}
 }
@@ -36,6 +36,7 @@ func Test_main(t *testing.T) {
 		"nri-rabbitmq",
 		"-node_name_override", "node1",
 		"-config_path", "",
+		"-temp_dir", t.TempDir(),
 		"-hostname", args.GlobalArgs.Hostname,
 		"-port", strconv.Itoa(args.GlobalArgs.Port),
 	}
@@ -89,7 +90,49 @@ func Test_getNeededData(t *testing.T) {
 	assert.Equal(t, 1, len(rabbitData.nodes))
 	assert.Equal(t, 1, len(rabbitData.queues))
 	assert.Equal(t, 1, len(rabbitData.vhosts))
+	assert.Equal(t, 1, len(rabbitData.shovels))
+	assert.Equal(t, 1, len(rabbitData.federation))
 
 	metricData := getMetricEntities(rabbitData)
-	assert.Equal(t, 3, len(metricData))
+	assert.Equal(t, 5, len(metricData))
+}
+
+func Test_getNeededData_NotClusterCollector(t *testing.T) {
+	mux, closer := testutils.GetTestServer(false)
+	defer closer()
+	var requested []string
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.RequestURI)
+		w.Header().Add("content-type", "application/json")
+		switch r.RequestURI {
+		case client.NodesEndpoint:
+			fmt.Fprint(w, `[{"name": "rabbit@a", "running": true}, {"name": "rabbit@b", "running": true}]`)
+		case client.OverviewEndpoint:
+			fmt.Fprint(w, "{}")
+		default:
+			fmt.Fprint(w, "[{}]")
+		}
+	})
+	existingArgs := args.GlobalArgs
+	defer func() {
+		args.GlobalArgs = existingArgs
+	}()
+	args.GlobalArgs.Metrics = true
+	args.GlobalArgs.ClusterEntities = args.ClusterEntitiesAuto
+	args.GlobalArgs.NodeNameOverride = "rabbit@b"
+
+	rabbitData := getNeededData()
+	assert.Equal(t, "rabbit@b", rabbitData.localNodeName)
+	assert.False(t, args.GlobalArgs.ReportsClusterEntities())
+	assert.Len(t, rabbitData.nodes, 2)
+	assert.Len(t, rabbitData.connections, 1)
+	assert.Empty(t, rabbitData.queues)
+	assert.Empty(t, rabbitData.exchanges)
+	assert.Empty(t, rabbitData.vhosts)
+	assert.NotContains(t, requested, client.QueuesEndpoint)
+
+	args.GlobalArgs.NodeNameOverride = "rabbit@a"
+	rabbitData = getNeededData()
+	assert.True(t, args.GlobalArgs.ReportsClusterEntities())
+	assert.Len(t, rabbitData.queues, 1)
 }
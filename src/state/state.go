@@ -0,0 +1,94 @@
+// Package state keeps the conditions checked by the events between runs, so events are only added when the conditions
+// change. The Check functions don't save anything: the changes are saved by the Record functions once their events
+// are added, so an event that couldn't be added is retried in the next run.
+package state
+
+import (
+	"sort"
+	"time"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/log"
+	"github.com/newrelic/infra-integrations-sdk/v3/persist"
+)
+
+// Now is replaced in tests
+var Now = time.Now
+
+// Transition is how a failing condition changed since the previous run
+type Transition int
+
+// The transitions returned by Check
+const (
+	NoTransition Transition = iota
+	StartedFailing
+	Recovered
+)
+
+// Check returns how the condition identified by key changed since the previous run and, when it recovers, how long
+// it was failing for
+func Check(store persist.Storer, key string, failing bool) (Transition, time.Duration) {
+	var since int64
+	_, err := store.Get(key, &since)
+	wasFailing := err == nil
+
+	switch {
+	case failing && !wasFailing:
+		return StartedFailing, 0
+	case failing:
+		// set on every run while failing, so the entry is kept alive in the store
+		store.Set(key, since)
+		return NoTransition, 0
+	case wasFailing:
+		return Recovered, time.Duration(Now().Unix()-since) * time.Second
+	}
+	return NoTransition, 0
+}
+
+// Record saves the transition returned by Check for the condition identified by key
+func Record(store persist.Storer, key string, change Transition) {
+	switch change {
+	case StartedFailing:
+		store.Set(key, Now().Unix())
+	case Recovered:
+		if err := store.Delete(key); err != nil {
+			log.Warn("Error deleting state [%s]: %v", key, err)
+		}
+	}
+}
+
+// CheckMembers compares the names with the ones saved under key by RecordMembers, returning the names added and
+// removed since then, both sorted. It returns false when no names were saved yet.
+func CheckMembers(store persist.Storer, key string, current []string) (added, removed []string, found bool) {
+	var previous []string
+	if _, err := store.Get(key, &previous); err != nil {
+		return sorted(current), nil, false
+	}
+	seen := make(map[string]bool, len(previous))
+	for _, name := range previous {
+		seen[name] = true
+	}
+	for _, name := range sorted(current) {
+		if seen[name] {
+			delete(seen, name)
+		} else {
+			added = append(added, name)
+		}
+	}
+	for _, name := range sorted(previous) {
+		if seen[name] {
+			removed = append(removed, name)
+		}
+	}
+	return added, removed, true
+}
+
+// RecordMembers saves the names compared by CheckMembers, it's called on every run so the entry is kept alive
+func RecordMembers(store persist.Storer, key string, current []string) {
+	store.Set(key, sorted(current))
+}
+
+func sorted(names []string) []string {
+	result := append([]string{}, names...)
+	sort.Strings(result)
+	return result
+}
@@ -0,0 +1,53 @@
+package state
+
+import (
+	"testing"
+	"time"
+
+	"github.com/newrelic/infra-integrations-sdk/v3/persist"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheck_NotRecorded(t *testing.T) {
+	defer func() {
+		Now = time.Now
+	}()
+	store := persist.NewInMemoryStore()
+
+	Now = func() time.Time { return time.Unix(1000, 0) }
+	change, _ := Check(store, "healthcheck/node1", true)
+	assert.Equal(t, StartedFailing, change)
+	change, _ = Check(store, "healthcheck/node1", true)
+	assert.Equal(t, StartedFailing, change, "a transition whose event wasn't added is reported again")
+	Record(store, "healthcheck/node1", change)
+	change, _ = Check(store, "healthcheck/node1", true)
+	assert.Equal(t, NoTransition, change)
+
+	Now = func() time.Time { return time.Unix(1030, 0) }
+	change, duration := Check(store, "healthcheck/node1", false)
+	assert.Equal(t, Recovered, change)
+	assert.Equal(t, 30*time.Second, duration)
+	change, _ = Check(store, "healthcheck/node1", false)
+	assert.Equal(t, Recovered, change, "a recovery whose event wasn't added is reported again")
+	Record(store, "healthcheck/node1", change)
+	change, _ = Check(store, "healthcheck/node1", false)
+	assert.Equal(t, NoTransition, change)
+}
+
+func TestCheckMembers(t *testing.T) {
+	store := persist.NewInMemoryStore()
+
+	added, removed, found := CheckMembers(store, "members", []string{"b", "a"})
+	assert.False(t, found)
+	assert.Equal(t, []string{"a", "b"}, added)
+	assert.Empty(t, removed)
+	RecordMembers(store, "members", []string{"b", "a"})
+
+	added, removed, found = CheckMembers(store, "members", []string{"d", "b"})
+	assert.True(t, found)
+	assert.Equal(t, []string{"d"}, added)
+	assert.Equal(t, []string{"a"}, removed)
+
+	_, removed, _ = CheckMembers(store, "members", nil)
+	assert.Equal(t, []string{"a", "b"}, removed, "nothing is saved until RecordMembers is called")
+}